@@ -6,65 +6,55 @@ import (
 	"path/filepath"
 
 	"Erato/erato/utils"
+	"errors"
 	"flag"
 	"fmt"
 	"log"
 
 	"os"
-
-	"github.com/joho/godotenv"
 )
 
 const (
-	DefaultEnv = "DONOTUSE"
+	DefaultConf = "./conf/Erato_Example.yml"
 )
 
-var env string
+var confFile string
 
 func init() {
-	env = os.Getenv("ENV")
+	confFile = os.Getenv("ERATO_CONF")
 }
 
 func main() {
 	// var err error
 
-	// Setup Config from environment variables or flags
-	if env == "" {
-		flag.StringVar(&env, "env", DefaultEnv, "Environment name to select the right .env file e.g. -name prod looks for .env.prod ")
-		flag.Parse()
+	// Sub commands
+	if len(os.Args) > 2 && os.Args[1] == "config" && os.Args[2] == "validate" {
+		os.Exit(configValidate(os.Args[3:]))
 	}
 
-	// if !isRunningInDockerContainer() {
-	// loads values from .env into the system
-	fn := ".env_" + env
-	if err := godotenv.Load(fn); err != nil {
-		log.Printf("No .env file found %v\n", fn)
-		os.Exit(1)
+	// Setup Config from environment variables or flags
+	if confFile == "" {
+		flag.StringVar(&confFile, "conf", DefaultConf, "Erato YML config file e.g. -conf ./conf/Erato_Example.yml")
+		flag.Parse()
 	}
 
-	// New Erato Object
-	e, err := erato.NewErato(env)
+	// Read the YML config file
+	conf, err := erato.NewConf2(confFile)
 	if err != nil {
 		log.Fatal(err)
 	}
 
-	// Temp Hack to create the content collction and source
-	fooColl := erato.Collection{Name: "BJSS Bid Documents",
-
-		ContentSource: erato.ContentSource{Name: "BJSS_Bid_SharePoint",
-			Collector: e.EratoCollectors.Sharepoint,
-		},
-		ContentPreparer: e.EratoPreparer,
-		ContentAnalyser: e.EratoAnalysers.OpenAI,
-		Conf:            e.Conf,
+	// New Erato Object with the Collections wired up from the config
+	e, err := erato.NewErato2(conf)
+	if err != nil {
+		log.Fatal(err)
 	}
-	// Append the content to the collections
-	e.ContentCollections = append(e.ContentCollections, fooColl)
 
 	// Loop though all the content sources
 	// Create a cataglog of all the content
 	// take the results from the Source specific Catalog function and add to the Erato Content Catalog
-	for _, collection := range e.ContentCollections {
+	for i := range e.ContentCollections {
+		collection := &e.ContentCollections[i]
 
 		// Catalogue the contenst of the Content source
 		fmt.Printf("Cataloging ContentSource=%v\n", collection.Name)
@@ -86,6 +76,12 @@ func main() {
 			log.Fatal(err)
 		}
 
+		// Write the site structure for websites
+		err = collection.ExportLinkGraph()
+		if err != nil {
+			log.Println(err)
+		}
+
 		// Add the Found stats
 		collection.ContentCatalogsStats.Found = len(collection.ContentCatalog)
 
@@ -100,6 +96,20 @@ func main() {
 			log.Fatal(err)
 		}
 
+		// Store the content catalog, tombstoning the analysis of deleted documents
+		err = collection.StoreContentCatalog()
+		if err != nil {
+			// TODO - Replace with logging
+			log.Fatal(err)
+		}
+
+		// The collector keeps its state for the next run only once the analysis is stored
+		err = collection.CommitContentCatalog()
+		if err != nil {
+			// TODO - Replace with logging
+			log.Fatal(err)
+		}
+
 	}
 
 	// write Erato Content Catalogue to a file
@@ -122,3 +132,43 @@ func main() {
 	fmt.Println("*____________________________________________________________________________________*")
 
 }
+
+// configValidate - erato config validate [-conf file]
+// Reports every problem in the config file and returns the exit code
+func configValidate(args []string) int {
+	fs := flag.NewFlagSet("config validate", flag.ExitOnError)
+	cf := fs.String("conf", DefaultConf, "Erato YML config file to validate")
+	fs.Parse(args)
+
+	if confFile != "" && !isFlagSet(fs, "conf") {
+		*cf = confFile
+	}
+
+	_, err := erato.NewConf2(*cf)
+
+	var ces erato.ConfigErrors
+	if errors.As(err, &ces) {
+		fmt.Printf("Erato - Config:%v has %v problem(s)\n", *cf, len(ces))
+		for _, ce := range ces {
+			fmt.Printf("\t%v\n", ce.Error())
+		}
+		return 1
+	} else if err != nil {
+		fmt.Printf("Erato - Config:%v - %v\n", *cf, err)
+		return 1
+	}
+
+	fmt.Printf("Erato - Config:%v is valid\n", *cf)
+	return 0
+}
+
+// isFlagSet - Check if the flag was passed on the command line
+func isFlagSet(fs *flag.FlagSet, name string) bool {
+	set := false
+	fs.Visit(func(f *flag.Flag) {
+		if f.Name == name {
+			set = true
+		}
+	})
+	return set
+}
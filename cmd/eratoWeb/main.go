@@ -8,76 +8,44 @@ import (
 	"log"
 
 	"os"
-
-	"github.com/joho/godotenv"
 )
 
 const (
-	DefaultEnv = "DONOTUSE"
+	DefaultConf = "./conf/Erato_WebCollector.yml"
 )
 
-var env string
+var confFile string
 
 func init() {
-	env = os.Getenv("ENV")
+	confFile = os.Getenv("ERATO_CONF")
 }
 
 func main() {
 	// var err error
 
 	// Setup Config from environment variables or flags
-	if env == "" {
-		flag.StringVar(&env, "env", DefaultEnv, "Environment name to select the right .env file e.g. -name prod looks for .env.prod ")
+	if confFile == "" {
+		flag.StringVar(&confFile, "conf", DefaultConf, "Erato YML config file e.g. -conf ./conf/Erato_WebCollector.yml")
 		flag.Parse()
 	}
 
-	// if !isRunningInDockerContainer() {
-	// loads values from .env into the system
-	fn := ".env_" + env
-	if err := godotenv.Load(fn); err != nil {
-		log.Printf("Erato - Error loading .env file:%v Error:%v\n", fn, err)
-		os.Exit(1)
-	}
-
-	/*
-		// Read the YML config file
-		conf2, err := erato.NewConf2("erato.yml")
-		if err != nil {
-			log.Fatal(err)
-		}
-
-		e2, err := erato.NewErato2(conf2)
-		if err != nil {
-			log.Fatal(err)
-		}
-
-		fmt.Printf("Config=%v\n", e2)
-
-	*/
-
-	// New Erato Object
-	e, err := erato.NewErato(env)
+	// Read the YML config file
+	conf, err := erato.NewConf2(confFile)
 	if err != nil {
 		log.Fatal(err)
 	}
 
-	fooColl := erato.Collection{Name: "Collection 1",
-
-		ContentSource: erato.ContentSource{Name: "website collector",
-			Collector: e.EratoCollectors.Website,
-		},
-		ContentPreparer: e.EratoPreparer,
-		ContentAnalyser: e.EratoAnalysers.OpenAI,
-		Conf:            e.Conf,
+	// New Erato Object with the Collections wired up from the config
+	e, err := erato.NewErato2(conf)
+	if err != nil {
+		log.Fatal(err)
 	}
 
-	// Append the content to the collections
-	e.ContentCollections = append(e.ContentCollections, fooColl)
-
 	// Loop though all the content sources
 	// Create a cataglog of all the content
 	// take the results from the Source specific Catalog function and add to the Erato Content Catalog
-	for _, collection := range e.ContentCollections {
+	for i := range e.ContentCollections {
+		collection := &e.ContentCollections[i]
 
 		// Catalogue the contenst of the Content source
 		fmt.Printf("Cataloging ContentSource=%v\n", collection.Name)
@@ -99,6 +67,12 @@ func main() {
 			log.Fatal(err)
 		}
 
+		// Write the site structure for websites
+		err = collection.ExportLinkGraph()
+		if err != nil {
+			log.Println(err)
+		}
+
 		// Dump the filenames if debug set
 		if e.Conf.Debug {
 			collection.DumpCatalogFileNames()
@@ -120,6 +94,13 @@ func main() {
 			log.Fatal(err)
 		}
 
+		// The collector keeps its state for the next run only once the analysis is stored
+		err = collection.CommitContentCatalog()
+		if err != nil {
+			// TODO - Replace with logging
+			log.Fatal(err)
+		}
+
 	}
 
 }
@@ -7,6 +7,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"log"
+	"sort"
 	"strings"
 	"sync"
 	"time"
@@ -52,6 +53,10 @@ type ContentAnalysisData struct {
 	AnalysisResults []Analysis
 	AnalysisStats   ContentAnalysisStats
 	AnalysisErrors  []error
+	// Metadata from the content source e.g. SharePoint columns, used in the prompt
+	MetaData map[string]string
+	// Where each text chunk came from, indexed by ParagraphNum-1
+	ChunkMetaData []models.ChunkMetaData
 }
 
 // Depricated
@@ -87,9 +92,13 @@ type AnalysisData map[string]interface{}
 // AnalysisMetaDaya - Meta Data for the Analysis
 // Added as a key _EratoMetaData to the AnalysisData
 type AnalysisMetaData struct {
-	ParagraphNum  int
+	ParagraphNum int
+	// Where the paragraph came from e.g. the page, slide or sheet and cell range
+	models.ChunkMetaData
 	AnalysisError error
 	ResponseInfo  openai.ChatCompletionResponse
+	// The metadata from the content source e.g. SharePoint columns to compare with the extracted entities
+	SourceMetaData map[string]string `json:",omitempty"`
 	// TODO: date and time, and other meta data
 }
 
@@ -140,6 +149,57 @@ func (oai *OpenAI) NewContentAnalysis(EratoID string, content []string) models.C
 	return &cad
 }
 
+// SetChunkMetaData - Set where each text chunk came from for the results
+func (ca *ContentAnalysisData) SetChunkMetaData(chunkMetaData []models.ChunkMetaData) {
+	ca.ChunkMetaData = chunkMetaData
+}
+
+// chunkMetaData - Where the paragraph came from, empty when the preparer doesn't record it
+func (ca *ContentAnalysisData) chunkMetaData(paragraphNum int) models.ChunkMetaData {
+	if paragraphNum < 1 || paragraphNum > len(ca.ChunkMetaData) {
+		return models.ChunkMetaData{}
+	}
+	return ca.ChunkMetaData[paragraphNum-1]
+}
+
+// MetaDataPlaceholder - Replaced in the prompt with the source metadata of the document
+const MetaDataPlaceholder = "{{MetaData}}"
+
+// SetMetaData - Set the source metadata of the document for the prompt
+func (ca *ContentAnalysisData) SetMetaData(metaData map[string]string) {
+	ca.MetaData = metaData
+}
+
+// metaDataSection - Added to the end of a prompt without the MetaDataPlaceholder when there is metadata
+const metaDataSection = "\n\nThe source of the document records the following about it, use it to help identify the Client Name and Organisation Names:\n"
+
+// promptWithMetaData - Replace the MetaDataPlaceholder in the prompt with a "Name: Value" line for each metadata value,
+// "None" when there is no metadata. A prompt without the placeholder has the metadata added in a section at the end
+func promptWithMetaData(prompt string, metaData map[string]string) string {
+	names := make([]string, 0, len(metaData))
+	for name := range metaData {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var lines []string
+	for _, name := range names {
+		lines = append(lines, fmt.Sprintf("%v: %v", name, metaData[name]))
+	}
+
+	if strings.Contains(prompt, MetaDataPlaceholder) {
+		if len(lines) == 0 {
+			lines = []string{"None"}
+		}
+		return strings.ReplaceAll(prompt, MetaDataPlaceholder, strings.Join(lines, "\n"))
+	}
+
+	if len(lines) == 0 {
+		return prompt
+	}
+	return prompt + metaDataSection + strings.Join(lines, "\n")
+}
+
 // func (oai *OpenAI) AnalyseContent(textChunks []string) ([]interface{}, error) {
 // AnalyseTextChunks - Analyse the text chunks and add the results to the Content Analysis Object
 func (ca *ContentAnalysisData) AnalyseContent() error {
@@ -157,6 +217,7 @@ func (ca *ContentAnalysisData) AnalyseContent() error {
 	analyserWorkerCount := ca.OpenAI.OAIparralelRequests
 	TextChunks := ca.Content
 	analyser := ca.OpenAI
+	prompt := promptWithMetaData(analyser.OIAprompt, ca.MetaData)
 
 	// var chunkmetaData interface{}
 	NumTextChunks := len(TextChunks)
@@ -197,7 +258,7 @@ func (ca *ContentAnalysisData) AnalyseContent() error {
 
 		// Now process the text chunk
 		go func(textChunk string, i int) {
-			analyseTextChunk(analyser, &textChunk, i, &wg, textAnalysisResultChan)
+			analyseTextChunk(analyser, prompt, &textChunk, i, &wg, textAnalysisResultChan)
 		}(textChunk, i)
 
 		if debug {
@@ -246,6 +307,8 @@ func (ca *ContentAnalysisData) AnalyseContent() error {
 		a.AnalysisData = result.AnalysisData
 		// TODO - Check that analysis is being added
 		a.AnalysisMetaData.ParagraphNum = result.Order
+		a.AnalysisMetaData.ChunkMetaData = ca.chunkMetaData(result.Order)
+		a.AnalysisMetaData.SourceMetaData = ca.MetaData
 		a.AnalysisMetaData.ResponseInfo = result.ResponseInfo
 
 		// a.AnalysisMetaData.AnalysisError = result.Err
@@ -261,7 +324,7 @@ func (ca *ContentAnalysisData) AnalyseContent() error {
 }
 
 // Change to conectSource orientated
-func analyseTextChunk(analyser *OpenAI, textChunk *string, i int, wg *sync.WaitGroup, resultChan chan<- TextChunkAnalysis) {
+func analyseTextChunk(analyser *OpenAI, prompt string, textChunk *string, i int, wg *sync.WaitGroup, resultChan chan<- TextChunkAnalysis) {
 	defer wg.Done()
 	debug := analyser.AnalyserDebug()
 	wordCount := len(strings.Fields(*textChunk))
@@ -271,7 +334,7 @@ func analyseTextChunk(analyser *OpenAI, textChunk *string, i int, wg *sync.WaitG
 	}
 
 	// Extact the entities from the text chunk into a string
-	ee, err := analyser.ExtractEntities(i, textChunk)
+	ee, err := analyser.ExtractEntities(i, prompt, textChunk)
 	if err != nil {
 		// write the error back to the channel
 		fmt.Printf("\t\topenai.analyseTextChunk - DEBUG - Ending with ERROR Paragraph:%v Error:%v\n", i, err)
@@ -345,7 +408,7 @@ type ExtractEntitiesResponse struct {
 }
 
 // ExtractEntities - User OpenAI to generate a JSON of Entity Extracts based on the prompt
-func (c *OpenAI) ExtractEntities(i int, paraText *string) (ExtractEntitiesResponse, error) {
+func (c *OpenAI) ExtractEntities(i int, prompt string, paraText *string) (ExtractEntitiesResponse, error) {
 	var err error
 	var resp openai.ChatCompletionResponse
 	var eer ExtractEntitiesResponse
@@ -398,7 +461,7 @@ func (c *OpenAI) ExtractEntities(i int, paraText *string) (ExtractEntitiesRespon
 			// Structured Prompt
 			{
 				Role:    openai.ChatMessageRoleSystem,
-				Content: c.OIAprompt,
+				Content: prompt,
 			},
 			{
 				Role:    openai.ChatMessageRoleUser,
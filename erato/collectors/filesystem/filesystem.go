@@ -0,0 +1,404 @@
+package filesystem
+
+import (
+	"Erato/erato/utils"
+	"archive/zip"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// Filesystem Collector Object
+// Walks a local (or mounted network share) directory tree and catalogs the files
+type FilesystemCollector struct {
+	Config         *FilesystemConfig
+	RootDir        string
+	DepthLimit     int
+	ExcludedPath   []string
+	FollowSymlinks bool
+	ExpandArchives bool
+	AllFiles       []File
+	Debug          bool
+	// Real paths of the directories already walked - stops symlink loops
+	visitedDirs map[string]bool
+}
+
+type FilesystemConfig struct {
+	Name           string
+	RootDir        string
+	DepthLimit     int
+	ExcludedPath   []string
+	FollowSymlinks bool
+	ExpandArchives bool
+	Debug          bool
+}
+
+// File - Filesystem File definition
+type File struct {
+	I                int
+	UniqueID         string
+	Name             string
+	FileTypeName     string
+	FullPath         string
+	RelativePath     string
+	ArchivePath      string
+	ArchiveEntry     string
+	Location         string
+	Level            int
+	Size             int64
+	TimeLastModified time.Time
+}
+
+// NewCollector - Create a new Filesystem Collector object
+func NewCollector(cc interface{}) (*FilesystemCollector, error) {
+	var err error
+	// Asert the config to the FilesystemConfig
+	c, ok := cc.(*FilesystemConfig)
+	if !ok {
+		return nil, fmt.Errorf("NewFilesystemCollector - Error asserting config type")
+	}
+
+	if c.RootDir == "" {
+		return nil, fmt.Errorf("NewFilesystemCollector - RootDir has no value")
+	}
+
+	root, err := filepath.Abs(c.RootDir)
+	if err != nil {
+		return nil, fmt.Errorf("NewFilesystemCollector - unable to resolve RootDir:%v - %v", c.RootDir, err)
+	}
+
+	fi, err := os.Stat(root)
+	if err != nil {
+		return nil, fmt.Errorf("NewFilesystemCollector - unable to read RootDir:%v - %v", root, err)
+	}
+	if !fi.IsDir() {
+		return nil, fmt.Errorf("NewFilesystemCollector - RootDir is not a directory:%v", root)
+	}
+
+	fsc := FilesystemCollector{
+		Config:         c,
+		RootDir:        root,
+		DepthLimit:     c.DepthLimit,
+		ExcludedPath:   c.ExcludedPath,
+		FollowSymlinks: c.FollowSymlinks,
+		ExpandArchives: c.ExpandArchives,
+		Debug:          c.Debug,
+	}
+
+	return &fsc, err
+}
+
+// CatalogContents - Walk the directory tree from the RootDir
+//
+//	Recurse through the folder hierarchy (as deep as the config setting allows)
+//	skipping the excluded paths and applying the symlink policy
+func (fsc *FilesystemCollector) CatalogContents() error {
+
+	fsc.AllFiles = nil
+	fsc.visitedDirs = make(map[string]bool)
+
+	if fsc.Debug {
+		fmt.Println("CatalogContents - RootDir=", fsc.RootDir)
+	}
+
+	err := fsc.walkDir(fsc.RootDir, 0)
+	if err != nil {
+		return fmt.Errorf("CatalogContents - Error occured walking %v: %v", fsc.RootDir, err)
+	}
+
+	return err
+}
+
+// walkDir - Allow the recursion to get the files and folders in a directory
+func (fsc *FilesystemCollector) walkDir(dir string, level int) error {
+
+	// Record the real path so a symlink back up the tree is only walked once
+	realDir, err := filepath.EvalSymlinks(dir)
+	if err != nil {
+		return fmt.Errorf("walkDir - unable to resolve:%v - %v", dir, err)
+	}
+	if fsc.visitedDirs[realDir] {
+		if fsc.Debug {
+			fmt.Printf("walkDir - DEBUG - Already visited:%v\n", dir)
+		}
+		return nil
+	}
+	fsc.visitedDirs[realDir] = true
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return fmt.Errorf("walkDir - unable to read directory:%v - %v", dir, err)
+	}
+
+	for _, entry := range entries {
+		fp := filepath.Join(dir, entry.Name())
+
+		// Work out what the entry really is, following the link if the policy allows
+		info, err := entry.Info()
+		if err != nil {
+			fmt.Printf("walkDir - Warning - unable to stat:%v - %v\n", fp, err)
+			continue
+		}
+
+		if info.Mode()&os.ModeSymlink != 0 {
+			if !fsc.FollowSymlinks {
+				if fsc.Debug {
+					fmt.Printf("walkDir - DEBUG - Skipping symlink:%v\n", fp)
+				}
+				continue
+			}
+
+			info, err = os.Stat(fp)
+			if err != nil {
+				fmt.Printf("walkDir - Warning - broken symlink:%v - %v\n", fp, err)
+				continue
+			}
+		}
+
+		if info.IsDir() {
+			// Filter out the excluded directories rather than walking them
+			if fsc.excluded(fp) {
+				if fsc.Debug {
+					fmt.Printf("walkDir - DEBUG - Path Filtered out:%v\n", fp)
+				}
+				continue
+			}
+
+			// limit the depth of the recursion
+			if fsc.DepthLimit != 0 && level+1 > fsc.DepthLimit {
+				continue
+			}
+
+			err = fsc.walkDir(fp, level+1)
+			if err != nil {
+				return err
+			}
+			continue
+		}
+
+		// Only regular files are content
+		if !info.Mode().IsRegular() {
+			continue
+		}
+
+		file := fsc.newFile(fp, info, level)
+
+		if fsc.ExpandArchives && strings.EqualFold(file.FileTypeName, ".zip") {
+			err = fsc.catalogArchive(file)
+			if err != nil {
+				fmt.Printf("walkDir - Warning - unable to read archive:%v - %v\n", fp, err)
+			}
+			continue
+		}
+
+		fsc.AllFiles = append(fsc.AllFiles, file)
+	}
+
+	return nil
+}
+
+// catalogArchive - Add the entries of a zip archive as if they were a directory
+func (fsc *FilesystemCollector) catalogArchive(archive File) error {
+
+	zr, err := zip.OpenReader(archive.FullPath)
+	if err != nil {
+		return err
+	}
+	defer zr.Close()
+
+	for _, f := range zr.File {
+		if f.FileInfo().IsDir() {
+			continue
+		}
+
+		entryDir := filepath.Dir(filepath.FromSlash(f.Name))
+		if fsc.excluded(entryDir) {
+			continue
+		}
+
+		level := archive.Level + strings.Count(filepath.ToSlash(entryDir), "/") + 1
+		if fsc.DepthLimit != 0 && level > fsc.DepthLimit {
+			continue
+		}
+
+		fullPath := filepath.Join(archive.FullPath, filepath.FromSlash(f.Name))
+		relPath := filepath.Join(archive.RelativePath, filepath.FromSlash(f.Name))
+
+		file := File{
+			I:                len(fsc.AllFiles),
+			UniqueID:         utils.HashString(fullPath),
+			Name:             filepath.Base(f.Name),
+			FileTypeName:     strings.ToLower(filepath.Ext(f.Name)),
+			FullPath:         fullPath,
+			RelativePath:     relPath,
+			ArchivePath:      archive.FullPath,
+			ArchiveEntry:     f.Name,
+			Location:         archive.Location,
+			Level:            level,
+			Size:             int64(f.UncompressedSize64),
+			TimeLastModified: f.Modified,
+		}
+
+		fsc.AllFiles = append(fsc.AllFiles, file)
+	}
+
+	return nil
+}
+
+// newFile - map the file values from the filesystem to the File struct
+func (fsc *FilesystemCollector) newFile(fp string, info os.FileInfo, level int) File {
+	relPath, err := filepath.Rel(fsc.RootDir, fp)
+	if err != nil {
+		relPath = fp
+	}
+
+	return File{
+		I:                len(fsc.AllFiles),
+		UniqueID:         utils.HashString(fp),
+		Name:             info.Name(),
+		FileTypeName:     strings.ToLower(filepath.Ext(info.Name())),
+		FullPath:         fp,
+		RelativePath:     relPath,
+		Location:         fsc.location(),
+		Level:            level,
+		Size:             info.Size(),
+		TimeLastModified: info.ModTime(),
+	}
+}
+
+// location - Name used to group the files in the output
+func (fsc *FilesystemCollector) location() string {
+	if fsc.Config.Name != "" {
+		return strings.ReplaceAll(fsc.Config.Name, " ", "_")
+	}
+	return filepath.Base(fsc.RootDir)
+}
+
+// excluded - Check if the path contains any of the exclusions
+func (fsc *FilesystemCollector) excluded(fp string) bool {
+	for _, excludePath := range fsc.ExcludedPath {
+		// A empty value for the path means no exclusion
+		if excludePath != "" && strings.Contains(fp, excludePath) {
+			return true
+		}
+	}
+	return false
+}
+
+func (fsc *FilesystemCollector) DumpCatalogFileNames() {
+	for _, f := range fsc.AllFiles {
+		fmt.Printf("DumpCatalogFileNames - File=%v\n", f.FullPath)
+	}
+}
+
+// AllContentRefs - Return all the content references
+func (fsc *FilesystemCollector) AllContentRefs() []interface{} {
+	var retVal []interface{}
+
+	for i := range fsc.AllFiles {
+
+		// this specifc syntax is required to get the interface{} type into the slice
+		ff := interface{}(&fsc.AllFiles[i])
+		retVal = append(retVal, ff)
+
+	}
+
+	return retVal
+}
+
+// DownloadContentData - Read the file from disk (or from inside its archive)
+func (fsc *FilesystemCollector) DownloadContentData(ff interface{}) (*[]byte, error) {
+
+	// assert the file type
+	file, ok := ff.(*File)
+	if !ok {
+		return nil, fmt.Errorf("DownloadContentData - Error asserting file type")
+	}
+
+	var data []byte
+	var err error
+
+	if file.ArchivePath != "" {
+		data, err = readArchiveEntry(file.ArchivePath, file.ArchiveEntry)
+	} else {
+		data, err = os.ReadFile(file.FullPath)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("DownloadContentData - Error reading file:%v - %v", file.FullPath, err)
+	}
+
+	if fsc.Debug {
+		fmt.Printf("\n\tDownloadContentData - Read:%v - size:%v\n", file.Name, len(data))
+	}
+
+	return &data, err
+}
+
+// readArchiveEntry - Read a single entry out of a zip archive
+func readArchiveEntry(archivePath string, entry string) ([]byte, error) {
+	zr, err := zip.OpenReader(archivePath)
+	if err != nil {
+		return nil, err
+	}
+	defer zr.Close()
+
+	for _, f := range zr.File {
+		if f.Name != entry {
+			continue
+		}
+
+		rc, err := f.Open()
+		if err != nil {
+			return nil, err
+		}
+		defer rc.Close()
+
+		return io.ReadAll(rc)
+	}
+
+	return nil, fmt.Errorf("entry %v not found in archive %v", entry, archivePath)
+}
+
+// Functions to implement the ContentRef interface
+func (file *File) GetUniqueID() string {
+	return file.UniqueID
+}
+
+func (file *File) GetName() string {
+	return file.Name
+}
+
+func (file *File) GetFileName() string {
+	return file.FullPath
+}
+
+func (file *File) GetLocation() string {
+	return file.Location
+}
+
+func (file *File) GetTypeName() string {
+	return file.FileTypeName
+}
+
+func (file *File) GetPath() string {
+	return filepath.Dir(file.FullPath)
+}
+
+func (file *File) GetPathHash() string {
+	return utils.HashString(filepath.Dir(file.FullPath))
+}
+
+func (file *File) GetParentLocation() string {
+	return filepath.Dir(file.RelativePath)
+}
+
+func (file *File) GetTimeLastModified() time.Time {
+	return file.TimeLastModified
+}
+
+func (file *File) GetSize() int64 {
+	return file.Size
+}
@@ -0,0 +1,75 @@
+package sharepoint
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/koltyakov/gosip"
+	"github.com/koltyakov/gosip/auth/addin"
+	"github.com/koltyakov/gosip/auth/azurecert"
+	"github.com/koltyakov/gosip/auth/device"
+	"github.com/koltyakov/gosip/auth/ntlm"
+)
+
+// SharePoint auth strategies, the credentials for each are in the SPAuthFile
+// in the gosip private.json format - https://go.spflow.com/auth/overview
+const (
+	// AuthAzureCert - Azure AD app with a certificate: siteUrl, tenantId, clientId, certPath and certPass
+	AuthAzureCert = "azurecert"
+	// AuthAddin - SharePoint add-in only client secret: siteUrl, clientId, clientSecret and realm
+	AuthAddin = "addin"
+	// AuthDevice - Azure AD device code flow, signed in from a browser on the first run: siteUrl, clientId and tenantId
+	AuthDevice = "device"
+	// AuthNTLM - On-premises username and password: siteUrl, domain, username and password
+	AuthNTLM = "ntlm"
+)
+
+// AuthStrategies - The auth strategies that can be configured, the first is the default
+var AuthStrategies = []string{AuthAzureCert, AuthAddin, AuthDevice, AuthNTLM}
+
+// AuthStrategy - Check the auth strategy, blank is the default AuthAzureCert
+func AuthStrategy(strategy string) error {
+	if strategy == "" {
+		return nil
+	}
+	for _, s := range AuthStrategies {
+		if strings.EqualFold(s, strategy) {
+			return nil
+		}
+	}
+	return fmt.Errorf("AuthStrategy - %v is not one of %v", strategy, strings.Join(AuthStrategies, ", "))
+}
+
+// newAuthCnfg - The gosip auth for the strategy
+func newAuthCnfg(strategy string) (gosip.AuthCnfg, error) {
+	switch strings.ToLower(strategy) {
+	case "", AuthAzureCert:
+		return &azurecert.AuthCnfg{}, nil
+	case AuthAddin:
+		return &addin.AuthCnfg{}, nil
+	case AuthDevice:
+		return &device.AuthCnfg{}, nil
+	case AuthNTLM:
+		return &ntlm.AuthCnfg{}, nil
+	}
+	return nil, AuthStrategy(strategy)
+}
+
+// setupClient - The SharePoint client signed in with the configured strategy and SPAuthFile
+func setupClient(c *SharePointColector) (*gosip.SPClient, error) {
+	if c.SPAuthFile == "" {
+		return nil, fmt.Errorf("setupClient - SPAuthFile has no value")
+	}
+
+	authCnfg, err := newAuthCnfg(c.SPauthStrategy)
+	if err != nil {
+		return nil, fmt.Errorf("setupClient - %v", err)
+	}
+
+	err = authCnfg.ReadConfig(c.SPAuthFile)
+	if err != nil {
+		return nil, fmt.Errorf("setupClient - unable to read %v config file: %v - %v", authCnfg.GetStrategy(), c.SPAuthFile, err)
+	}
+
+	return &gosip.SPClient{AuthCnfg: authCnfg}, nil
+}
@@ -0,0 +1,406 @@
+package sharepoint
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/koltyakov/gosip/api"
+)
+
+// SharePoint change types - https://learn.microsoft.com/en-us/previous-versions/office/sharepoint-csom/ee543793(v=office.15)
+const (
+	changeTypeDeleteObject = 3
+	changeTypeMoveAway     = 5
+)
+
+// libraryState - What was catalogued from a document library, kept in the StateDir between runs
+// so the next run only catalogues the files added, updated and deleted since
+type libraryState struct {
+	Name        string
+	SiteURL     string
+	ChangeToken string
+	// The catalogued files by UniqueID, a deletion only has the ID of the file
+	Files map[string]File
+}
+
+// syncDocumentLibraryContents - Catalog the changes to the library since the last run
+// The first run, or one after the change token has expired, catalogs the whole library
+func (dl *DocumentLibrary) syncDocumentLibraryContents() error {
+	state, err := readLibraryState(dl.stateFile())
+	if err != nil {
+		return err
+	}
+
+	list := dl.site.web.Lists().GetByID(dl.ID)
+
+	// Taken before the library is read so changes made while it's read are caught next run
+	token, err := list.Changes().GetCurrentToken()
+	if err != nil {
+		return fmt.Errorf("syncDocumentLibraryContents - unable to get the change token for %v: %v", dl.Name, err)
+	}
+
+	synced := false
+	if state.ChangeToken != "" {
+		err = dl.catalogChanges(list, &state, token)
+		if err == nil {
+			synced = true
+		} else {
+			fmt.Printf("syncDocumentLibraryContents - WARNING - %v - cataloguing the whole library - %v\n", dl.Name, err)
+		}
+	}
+
+	if !synced {
+		err = dl.catalogAllContents(&state)
+		if err != nil {
+			return err
+		}
+	}
+
+	state.Name = dl.Name
+	state.SiteURL = dl.SiteURL
+	state.ChangeToken = token
+
+	// Written by CommitCatalog once the changes are analysed and stored so a run
+	// that fails or is stopped before then catalogs them again
+	dl.pendingState = &state
+
+	return nil
+}
+
+// CommitCatalog - Write the state of each library once the catalog has been analysed and stored
+// A library with a document whose analysis wasn't stored keeps the state of the last run
+// so the next run catalogs its changes again
+func (spc *SharePointColector) CommitCatalog(unstored []interface{}) error {
+	failed := make(map[string]bool)
+	for _, ref := range unstored {
+		if file, ok := ref.(*File); ok {
+			failed[file.UniqueID] = true
+		}
+	}
+
+	for i := range spc.DocumentLibraries {
+		dl := &spc.DocumentLibraries[i]
+		if dl.pendingState == nil {
+			continue
+		}
+
+		if dl.hasFile(failed) {
+			fmt.Printf("CommitCatalog - WARNING - %v in %v - not all documents were stored, the changes will be catalogued again\n", dl.Name, dl.SiteURL)
+			continue
+		}
+
+		err := writeLibraryState(dl.stateFile(), *dl.pendingState)
+		if err != nil {
+			return fmt.Errorf("CommitCatalog - %v", err)
+		}
+		dl.pendingState = nil
+	}
+
+	return nil
+}
+
+// hasFile - One of the files catalogued or deleted from the library has a UniqueID in ids
+func (dl *DocumentLibrary) hasFile(ids map[string]bool) bool {
+	for id := range dl.LibraryFiles {
+		if ids[id] {
+			return true
+		}
+	}
+	for _, file := range dl.DeletedFiles {
+		if ids[file.UniqueID] {
+			return true
+		}
+	}
+	return false
+}
+
+// stateFile - Where the library's state is kept in the SPstateDir
+func (dl *DocumentLibrary) stateFile() string {
+	return filepath.Join(dl.site.Config.SPstateDir, "library-"+strings.Trim(dl.ID, "{}")+".json")
+}
+
+// catalogAllContents - Walk the whole library, the files catalogued last time that are gone are deleted
+func (dl *DocumentLibrary) catalogAllContents(state *libraryState) error {
+	var root Folder
+	c := dl.site.Config
+
+	err := getFilesAndFolders(c, dl.site.web, dl, &root, 0)
+	if err != nil {
+		return fmt.Errorf("catalogAllContents - Error occured get files and folders: %v", err)
+	}
+	dl.FolderHierarchy = root
+
+	for id, file := range state.Files {
+		if _, ok := dl.LibraryFiles[id]; !ok {
+			dl.deleteFile(file)
+		}
+	}
+
+	state.Files = make(map[string]File)
+	for id, file := range dl.LibraryFiles {
+		state.Files[id] = file
+	}
+
+	return nil
+}
+
+// catalogChanges - Catalog the files added, updated and deleted between the state's change token and the token
+func (dl *DocumentLibrary) catalogChanges(list *api.List, state *libraryState, token string) error {
+	c := dl.site.Config
+
+	query := &api.ChangeQuery{
+		ChangeTokenStart: state.ChangeToken,
+		ChangeTokenEnd:   token,
+		Item:             true,
+		Add:              true,
+		Update:           true,
+		DeleteObject:     true,
+		Rename:           true,
+		Move:             true,
+		Restore:          true,
+		// Permissions changed on the item, changes inherited from the library or site
+		// are only picked up when the whole library is catalogued
+		RoleAssignmentAdd:    c.SPpermissions,
+		RoleAssignmentDelete: c.SPpermissions,
+	}
+
+	// The last change to each item decides if it is catalogued or deleted
+	var order []int
+	last := make(map[int]*api.ChangeInfo)
+
+	changes, err := list.Changes().GetChanges(query)
+	for {
+		if err != nil {
+			return fmt.Errorf("catalogChanges - unable to get changes: %v", err)
+		}
+
+		data := changes.Data()
+		if len(data) == 0 {
+			break
+		}
+
+		for _, change := range data {
+			if _, ok := last[change.ItemID]; !ok {
+				order = append(order, change.ItemID)
+			}
+			last[change.ItemID] = change
+		}
+
+		changes, err = changes.GetNextPage()
+	}
+
+	if c.Debug {
+		fmt.Printf("catalogChanges - %v - %v items changed\n", dl.Name, len(order))
+	}
+
+	// The folder depth of the changed files is limited the same as the whole library is walked
+	var rootURL string
+	if c.SPdepthLimit != 0 && len(order) > 0 {
+		root, err := list.RootFolder().Select("ServerRelativeUrl").Get()
+		if err != nil {
+			return fmt.Errorf("catalogChanges - unable to get the root folder of %v: %v", dl.Name, err)
+		}
+		rootURL = root.Data().ServerRelativeURL
+	}
+
+	for _, itemID := range order {
+		change := last[itemID]
+
+		if change.ChangeType == changeTypeDeleteObject || change.ChangeType == changeTypeMoveAway {
+			dl.deleteItem(state, change)
+			continue
+		}
+
+		file, contentType, found, err := dl.getChangedFile(list, itemID)
+		if err != nil {
+			return err
+		}
+
+		known, isKnown := state.Files[file.UniqueID]
+
+		// A folder, or the file has gone from the library since the change
+		if !found {
+			dl.deleteItem(state, change)
+			continue
+		}
+
+		// No longer passes the filters or is deeper than the depth limit so it's no longer catalogued
+		if !c.includeFile(file, contentType) || (rootURL != "" && !c.includeDepth(folderDepth(rootURL, file.ServerRelativeURL))) {
+			if isKnown {
+				dl.deleteFile(known)
+				delete(state.Files, known.UniqueID)
+			}
+			continue
+		}
+
+		// Renamed or moved - the analysis stored at the old path is deleted
+		if isKnown && known.ServerRelativeURL != file.ServerRelativeURL {
+			dl.deleteFile(known)
+		}
+
+		file.I = len(dl.LibraryFiles)
+		file.DocumentLibrary = dl.Name
+		file.SiteURL = dl.SiteURL
+		dl.LibraryFiles[file.UniqueID] = file
+		state.Files[file.UniqueID] = file
+	}
+
+	return nil
+}
+
+// getChangedFile - The file for the changed list item, not found when the item is a folder or no longer in the list
+func (dl *DocumentLibrary) getChangedFile(list *api.List, itemID int) (File, string, bool, error) {
+	var item struct {
+		FileSystemObjectType int             `json:"FileSystemObjectType"`
+		File                 json.RawMessage `json:"File"`
+		ContentType          struct {
+			Name string `json:"Name"`
+		} `json:"ContentType"`
+		FieldValuesAsText map[string]interface{} `json:"FieldValuesAsText"`
+		RoleAssignments   []roleAssignment       `json:"RoleAssignments"`
+	}
+	c := dl.site.Config
+
+	selects := []string{"FileSystemObjectType", "File", "ContentType/Name"}
+	expands := []string{"File", "ContentType"}
+	if len(c.SPcolumns) > 0 {
+		selects = append(selects, "FieldValuesAsText")
+		expands = append(expands, "FieldValuesAsText")
+	}
+	if c.SPpermissions {
+		selects = append(selects, permissionsSelect)
+		expands = append(expands, permissionsExpand)
+	}
+
+	res, found, err := dl.getListItem(list.Items().GetByID(itemID).Select(strings.Join(selects, ",")).Expand(strings.Join(expands, ",")))
+	if err != nil {
+		return File{}, "", false, fmt.Errorf("getChangedFile - %v item:%v - %v", dl.Name, itemID, err)
+	}
+	if !found {
+		return File{}, "", false, nil
+	}
+
+	err = json.Unmarshal(api.NormalizeODataItem(res), &item)
+	if err != nil {
+		return File{}, "", false, fmt.Errorf("getChangedFile - %v item:%v - %v", dl.Name, itemID, err)
+	}
+
+	// 0 is a file, 1 a folder
+	if item.FileSystemObjectType != 0 || len(item.File) == 0 {
+		return File{}, "", false, nil
+	}
+
+	spFile := api.FileResp(item.File)
+	file := mapFileValues(&spFile)
+	if len(c.SPcolumns) > 0 {
+		file.Columns = c.columnValues(item.FieldValuesAsText)
+	}
+	if c.SPpermissions {
+		file.Permissions = readPermissions(item.RoleAssignments)
+	}
+
+	return file, item.ContentType.Name, true, nil
+}
+
+// getListItem - GET the list item, not found when SharePoint responds 404 Not Found
+// The item is requested with the client rather than item.Get() for the response status
+func (dl *DocumentLibrary) getListItem(item *api.Item) ([]byte, bool, error) {
+	req, err := http.NewRequest("GET", item.ToURL(), nil)
+	if err != nil {
+		return nil, false, err
+	}
+	req.Header.Set("Accept", "application/json;odata=verbose")
+
+	resp, err := dl.site.client.Execute(req)
+	if resp != nil && resp.Body != nil {
+		defer resp.Body.Close()
+	}
+	if resp != nil && resp.StatusCode == http.StatusNotFound {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, err
+	}
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, false, err
+	}
+	return data, true, nil
+}
+
+// deleteItem - The changed item was deleted, when it's a folder the files catalogued in it are too
+func (dl *DocumentLibrary) deleteItem(state *libraryState, change *api.ChangeInfo) {
+	if file, ok := state.Files[change.UniqueID]; ok {
+		dl.deleteFile(file)
+		delete(state.Files, change.UniqueID)
+		return
+	}
+
+	if change.ServerRelativeURL == "" {
+		return
+	}
+	for id, file := range state.Files {
+		if strings.HasPrefix(file.ServerRelativeURL, change.ServerRelativeURL+"/") {
+			dl.deleteFile(file)
+			delete(state.Files, id)
+		}
+	}
+}
+
+// deleteFile - Emit the file as deleted so its stored analysis can be tombstoned
+func (dl *DocumentLibrary) deleteFile(file File) {
+	file.Deleted = true
+	dl.DeletedFiles = append(dl.DeletedFiles, file)
+}
+
+// readLibraryState - A library without a state file has no change token or files
+func readLibraryState(name string) (libraryState, error) {
+	state := libraryState{Files: make(map[string]File)}
+
+	data, err := os.ReadFile(name)
+	if os.IsNotExist(err) {
+		return state, nil
+	} else if err != nil {
+		return state, fmt.Errorf("readLibraryState - %v", err)
+	}
+
+	err = json.Unmarshal(data, &state)
+	if err != nil {
+		return state, fmt.Errorf("readLibraryState - %v:%v", name, err)
+	}
+	if state.Files == nil {
+		state.Files = make(map[string]File)
+	}
+
+	return state, nil
+}
+
+// writeLibraryState - Replace the state file so an interrupted write doesn't lose the last one
+func writeLibraryState(name string, state libraryState) error {
+	data, err := json.MarshalIndent(state, "", "\t")
+	if err != nil {
+		return fmt.Errorf("writeLibraryState - %v", err)
+	}
+
+	err = os.MkdirAll(filepath.Dir(name), 0755)
+	if err != nil {
+		return fmt.Errorf("writeLibraryState - %v", err)
+	}
+
+	err = os.WriteFile(name+".tmp", data, 0644)
+	if err != nil {
+		return fmt.Errorf("writeLibraryState - %v", err)
+	}
+
+	err = os.Rename(name+".tmp", name)
+	if err != nil {
+		return fmt.Errorf("writeLibraryState - %v", err)
+	}
+	return nil
+}
@@ -0,0 +1,67 @@
+package sharepoint
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// filesExpand - The list item properties expanded with the files in a folder, blank for none
+func (c *SharePointConfig) filesExpand() string {
+	var expand []string
+	if len(c.SPcontentTypes) > 0 {
+		expand = append(expand, "ListItemAllFields/ContentType")
+	}
+	if len(c.SPcolumns) > 0 {
+		expand = append(expand, "ListItemAllFields/FieldValuesAsText")
+	}
+	if c.SPpermissions {
+		for _, e := range strings.Split(permissionsExpand, ",") {
+			expand = append(expand, "ListItemAllFields/"+e)
+		}
+	}
+	return strings.Join(expand, ",")
+}
+
+// fileColumns - The configured columns from a file listed with its ListItemAllFields/FieldValuesAsText
+func (c *SharePointConfig) fileColumns(data []byte) map[string]string {
+	var file struct {
+		ListItemAllFields struct {
+			FieldValuesAsText map[string]interface{} `json:"FieldValuesAsText"`
+		} `json:"ListItemAllFields"`
+	}
+	if len(c.SPcolumns) == 0 || json.Unmarshal(data, &file) != nil {
+		return nil
+	}
+	return c.columnValues(file.ListItemAllFields.FieldValuesAsText)
+}
+
+// columnValues - The configured columns from the text values of a list item's fields
+// FieldValuesAsText has the labels of managed metadata terms rather than their IDs, ; separated
+// Columns are matched by their internal name e.g. Bid_x0020_Outcome or with spaces e.g. Bid Outcome,
+// blank values are left out
+func (c *SharePointConfig) columnValues(fieldValues map[string]interface{}) map[string]string {
+	columns := make(map[string]string)
+
+	for _, name := range c.SPcolumns {
+		// FieldValuesAsText escapes the _ in internal names as _x005f_
+		internal := strings.ReplaceAll(name, " ", "_x0020_")
+		value, ok := fieldValues[strings.ReplaceAll(internal, "_", "_x005f_")]
+		if !ok {
+			value, ok = fieldValues[internal]
+		}
+		if !ok || value == nil {
+			continue
+		}
+
+		text := strings.TrimSpace(fmt.Sprint(value))
+		if text != "" {
+			columns[name] = text
+		}
+	}
+
+	if len(columns) == 0 {
+		return nil
+	}
+	return columns
+}
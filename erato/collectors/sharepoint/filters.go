@@ -0,0 +1,121 @@
+package sharepoint
+
+import (
+	"fmt"
+	"path"
+	"strings"
+	"time"
+)
+
+// DefaultLibraries - The document libraries catalogued when no libraries are configured
+var DefaultLibraries = []string{"Documents", "Site Assets", "Translation Packages"}
+
+// LibraryPattern - Check a library include or exclude pattern
+// Patterns are library names or globs e.g. "Documents" or "Project *"
+func LibraryPattern(pattern string) error {
+	_, err := path.Match(strings.ToLower(pattern), "")
+	if err != nil {
+		return fmt.Errorf("LibraryPattern - %v:%v", pattern, err)
+	}
+	return nil
+}
+
+// ParseDate - A date "2006-01-02" or time "2006-01-02T15:04:05Z07:00", blank is the zero time
+func ParseDate(value string) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+
+	t, err := time.Parse("2006-01-02", value)
+	if err == nil {
+		return t, nil
+	}
+
+	t, err = time.Parse(time.RFC3339, value)
+	if err != nil {
+		return t, fmt.Errorf("ParseDate - %v is not a date 2006-01-02 or time 2006-01-02T15:04:05Z", value)
+	}
+	return t, nil
+}
+
+// includeLibrary - The library matches the included libraries and none of the excluded libraries
+// Names are matched ignoring case
+func (c *SharePointConfig) includeLibrary(name string) bool {
+	included := c.SPincludedLibraries
+	if len(included) == 0 {
+		included = DefaultLibraries
+	}
+
+	return matchLibrary(included, name) && !matchLibrary(c.SPexcludedLibraries, name)
+}
+
+func matchLibrary(patterns []string, name string) bool {
+	name = strings.ToLower(name)
+	for _, p := range patterns {
+		if ok, _ := path.Match(strings.ToLower(p), name); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// filesFilter - The OData filter for the files in a folder so SharePoint only returns the files
+// that would be catalogued, blank when there is nothing to filter on
+func (c *SharePointConfig) filesFilter() string {
+	var filters []string
+
+	if !c.SPmodifiedAfter.IsZero() {
+		filters = append(filters, fmt.Sprintf("TimeLastModified gt datetime'%v'", c.SPmodifiedAfter.UTC().Format(time.RFC3339)))
+	}
+	if c.SPminFileSize > 0 {
+		filters = append(filters, fmt.Sprintf("Length ge %v", c.SPminFileSize))
+	}
+	if c.SPmaxFileSize > 0 {
+		filters = append(filters, fmt.Sprintf("Length le %v", c.SPmaxFileSize))
+	}
+
+	// The content type is on the list item of the file
+	var contentTypes []string
+	for _, ct := range c.SPcontentTypes {
+		contentTypes = append(contentTypes, fmt.Sprintf("ListItemAllFields/ContentType/Name eq '%v'", strings.ReplaceAll(ct, "'", "''")))
+	}
+	if len(contentTypes) > 0 {
+		filters = append(filters, "("+strings.Join(contentTypes, " or ")+")")
+	}
+
+	return strings.Join(filters, " and ")
+}
+
+// includeDepth - The folder depth is within the SPdepthLimit, 0 is the library root and 0 is no limit
+func (c *SharePointConfig) includeDepth(depth int) bool {
+	return c.SPdepthLimit == 0 || depth <= c.SPdepthLimit
+}
+
+// folderDepth - How many folders down from the library root the file is, 0 for a file in the root
+func folderDepth(rootURL string, fileURL string) int {
+	rel := strings.TrimPrefix(strings.TrimPrefix(fileURL, strings.TrimSuffix(rootURL, "/")), "/")
+	return strings.Count(rel, "/")
+}
+
+// includeFile - The file passes the filters, for files not listed with the filesFilter e.g. changed files
+func (c *SharePointConfig) includeFile(file File, contentType string) bool {
+	if !c.SPmodifiedAfter.IsZero() && !file.TimeLastModified.After(c.SPmodifiedAfter) {
+		return false
+	}
+	if c.SPminFileSize > 0 && int64(file.Length) < c.SPminFileSize {
+		return false
+	}
+	if c.SPmaxFileSize > 0 && int64(file.Length) > c.SPmaxFileSize {
+		return false
+	}
+
+	if len(c.SPcontentTypes) == 0 {
+		return true
+	}
+	for _, ct := range c.SPcontentTypes {
+		if ct == contentType {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,77 @@
+package sharepoint
+
+import (
+	"Erato/erato/models"
+	"encoding/json"
+
+	"github.com/koltyakov/gosip/api"
+)
+
+// SharePoint principal types - https://learn.microsoft.com/en-us/previous-versions/office/sharepoint-server/ee541430(v=office.15)
+var principalTypes = map[int]string{
+	1: "User",
+	2: "DistributionList",
+	4: "SecurityGroup",
+	8: "SharePointGroup",
+}
+
+// roleAssignment - A list item role assignment expanded with its Member and RoleDefinitionBindings
+type roleAssignment struct {
+	Member struct {
+		Title         string `json:"Title"`
+		LoginName     string `json:"LoginName"`
+		PrincipalType int    `json:"PrincipalType"`
+	} `json:"Member"`
+	RoleDefinitionBindings []struct {
+		Name            string              `json:"Name"`
+		BasePermissions api.BasePermissions `json:"BasePermissions"`
+	} `json:"RoleDefinitionBindings"`
+}
+
+// permissionsExpand - The role assignments expanded with a list item
+const permissionsExpand = "RoleAssignments/Member,RoleAssignments/RoleDefinitionBindings"
+
+// permissionsSelect - The role assignment properties selected with a list item
+const permissionsSelect = "RoleAssignments/Member/Title,RoleAssignments/Member/LoginName,RoleAssignments/Member/PrincipalType," +
+	"RoleAssignments/RoleDefinitionBindings/Name,RoleAssignments/RoleDefinitionBindings/BasePermissions"
+
+// filePermissions - The read permissions of a file listed with its ListItemAllFields/RoleAssignments
+func (c *SharePointConfig) filePermissions(data []byte) []models.ContentPermission {
+	var file struct {
+		ListItemAllFields struct {
+			RoleAssignments []roleAssignment `json:"RoleAssignments"`
+		} `json:"ListItemAllFields"`
+	}
+	if !c.SPpermissions || json.Unmarshal(data, &file) != nil {
+		return nil
+	}
+	return readPermissions(file.ListItemAllFields.RoleAssignments)
+}
+
+// readPermissions - The users and groups whose roles let them view the list item
+// The role assignments of an item that inherits its permissions are those of its folder, library or site
+// so they are the effective ones. Roles without ViewListItems e.g. Limited Access are left out
+func readPermissions(assignments []roleAssignment) []models.ContentPermission {
+	var permissions []models.ContentPermission
+
+	for _, ra := range assignments {
+		var roles []string
+		for _, rd := range ra.RoleDefinitionBindings {
+			if api.HasPermissions(rd.BasePermissions, api.PermissionKind.ViewListItems) {
+				roles = append(roles, rd.Name)
+			}
+		}
+		if len(roles) == 0 {
+			continue
+		}
+
+		permissions = append(permissions, models.ContentPermission{
+			Principal:     ra.Member.Title,
+			LoginName:     ra.Member.LoginName,
+			PrincipalType: principalTypes[ra.Member.PrincipalType],
+			Roles:         roles,
+		})
+	}
+
+	return permissions
+}
@@ -1,24 +1,24 @@
 package sharepoint
 
 import (
+	"Erato/erato/models"
 	"Erato/erato/utils"
 	"fmt"
-	"log"
 	"path/filepath"
 	"strings"
 	"time"
 
 	"github.com/koltyakov/gosip"
 	"github.com/koltyakov/gosip/api"
-	strategy "github.com/koltyakov/gosip/auth/azurecert"
 )
 
 // SharePoint Collector Object
 type SharePointColector struct {
-	SPsite SharePointSite
-	// SubSites          []*SharePointSite // Treat them as-if they were a Full SP site
+	SPsite                   SharePointSite
 	SPdepthLimit             int
+	SPsubSiteDepth           int
 	SPAuthFile               string
+	SPauthStrategy           string
 	SPexcludedPath           []string
 	SPincludedFileExtensions []string
 	DocumentLibraries        []DocumentLibrary
@@ -28,30 +28,59 @@ type SharePointColector struct {
 }
 
 type SharePointConfig struct {
-	SPsiteName               string
-	SPsiteURL                string
-	SPdepthLimit             int
+	SPsiteName   string
+	SPsiteURL    string
+	SPdepthLimit int
+	// SPsubSiteDepth - How many levels of subsites to catalog, 0 catalogs only the site
+	SPsubSiteDepth           int
 	SPAuthFile               string
+	SPauthStrategy           string // One of the AuthStrategies, how to sign in with the SPAuthFile
 	SPexcludedPath           []string
 	SPincludedFileExtensions []string
-	Debug                    bool
+	// Library names or globs to catalog, the DefaultLibraries when blank, and to skip
+	SPincludedLibraries []string
+	SPexcludedLibraries []string
+	// Filters on the files pushed to the SharePoint query, zero values don't filter
+	SPmodifiedAfter time.Time
+	SPminFileSize   int64
+	SPmaxFileSize   int64
+	SPcontentTypes  []string
+	// SPcolumns - The list item columns captured for each file e.g. Client or Bid Outcome
+	SPcolumns []string
+	// SPpermissions - Capture the users and groups that can read each file
+	SPpermissions bool
+	// SPstateDir - Where the change token of each library is kept so later runs only catalog the changes
+	SPstateDir string
+	Debug      bool
 }
 
 type SharePointSite struct {
 	SiteName string
 	SiteURL  string
-	// isSubSite bool
-	spAPI  *api.SP
+	// Level - 0 for the site, 1 for its subsites, 2 for theirs
+	Level int
+	spAPI *api.SP
+	// client - Used directly when the response status is needed e.g. 404 Not Found
+	client *gosip.SPClient
+	// web - The Web API for the site, a subsite's is reached from its own URL
+	web    *api.Web
 	Config *SharePointConfig
 }
 
 type DocumentLibrary struct {
-	ID              string
-	Name            string
-	ParentWebURL    string
+	ID           string
+	Name         string
+	ParentWebURL string
+	// SiteURL - The site or subsite the library is in
+	SiteURL         string
 	Path            string
 	FolderHierarchy Folder
 	LibraryFiles    LibraryFiles
+	// DeletedFiles - Catalogued on an earlier run and deleted since
+	DeletedFiles []File
+	site         *SharePointSite
+	// pendingState - The state to write once the library's documents are stored, see CommitCatalog
+	pendingState *libraryState
 }
 
 type LibraryFiles map[string]File
@@ -96,7 +125,15 @@ type File struct {
 	TimeLastModified  time.Time `json:"TimeLastModified"`
 	// TmpData           []byte
 	DocumentLibrary string
-	spAPI           *api.SP
+	// SiteURL - The site or subsite the file's library is in
+	SiteURL string
+	// Deleted - The file was catalogued on an earlier run and has been deleted since
+	Deleted bool
+	// Columns - The configured list item columns as text, managed metadata as the term labels
+	Columns map[string]string `json:",omitempty"`
+	// Permissions - The users and groups that can read the file
+	Permissions []models.ContentPermission `json:",omitempty"`
+	spAPI       *api.SP
 }
 
 // ----------------------------------------------------------
@@ -125,7 +162,9 @@ func NewCollector(cc interface{}) (*SharePointColector, error) {
 			Config: c,
 		},
 		SPdepthLimit:             c.SPdepthLimit,
+		SPsubSiteDepth:           c.SPsubSiteDepth,
 		SPAuthFile:               c.SPAuthFile,
+		SPauthStrategy:           c.SPauthStrategy,
 		SPexcludedPath:           c.SPexcludedPath,
 		SPincludedFileExtensions: c.SPincludedFileExtensions,
 		Debug:                    c.Debug,
@@ -136,8 +175,7 @@ func NewCollector(cc interface{}) (*SharePointColector, error) {
 	if err != nil {
 		return nil, fmt.Errorf("NewSharePointCollector - unable to setup SP connection: %v", err)
 	}
-
-	// sps.GetSubSitesMock("Data/subSiteList.txt")
+	spc.SPsite.web = spc.SPsite.spAPI.Web()
 
 	return &spc, err
 
@@ -146,35 +184,21 @@ func NewCollector(cc interface{}) (*SharePointColector, error) {
 // Setup API connections to SharePoint
 func setupAPI(c *SharePointColector) (*api.SP, error) {
 
-	var err error
-
-	client := setupClient(c)
+	client, err := setupClient(c)
+	if err != nil {
+		return nil, err
+	}
 
 	// Debug mode is set int he funcion
 	setupHookHandlers(c, client)
+	c.SPsite.client = client
 	sp := api.NewSP(client)
 
 	return sp, err
 }
 
-func setupClient(c *SharePointColector) *gosip.SPClient {
-	authCnfg := &strategy.AuthCnfg{}
-	// SPconfigPath := "./secrets/private.json"
-
-	if c.SPAuthFile == "" {
-		log.Fatalf("Sharepoint setupClient - has no value")
-	}
-
-	if err := authCnfg.ReadConfig(c.SPAuthFile); err != nil {
-		log.Fatalf("Sharepoint setupClient - unable to read config file: %v - %v", c.SPAuthFile, err)
-	}
-
-	return &gosip.SPClient{AuthCnfg: authCnfg}
-
-}
-
 // CatalogContents - Of a Sharepoint site
-// Determine the document Libraries on the site.
+// Determine the subsites (as deep as the config setting allows) and the document Libraries on each of them.
 // Catalog the contents of the side by:
 //
 //	Recurse through the folder hierarchy (as deep as the config setting allows)
@@ -193,7 +217,13 @@ func (spc *SharePointColector) CatalogContents() error {
 		return fmt.Errorf("CatalogContents - Error occured getting site configuration: %v", err)
 	}
 
-	// sps.ListSubSitesMock("Data/subSiteList.txt")
+	// Find the subsites to catalog along with the site
+	spc.SubSites = nil
+	err = spc.UpdateSubSites(&spc.SPsite)
+	if err != nil {
+		return fmt.Errorf("CatalogContents - Error occured getting subsites: %v", err)
+	}
+
 	// Populate the Document Libraries in the sharepoint site object
 	err = spc.UpdateDocumentLibaries()
 	if err != nil {
@@ -204,16 +234,18 @@ func (spc *SharePointColector) CatalogContents() error {
 	// iterate through the document libraries and get the contents and update the LibraryFiles slice for a flat list
 	for i := range spc.DocumentLibraries {
 
-		// check a comma delimited list of libraries to include
-		// TODO - move list to a constant
-		if !strings.Contains("Documents,Site Assets,Translation Packages", spc.DocumentLibraries[i].Name) {
+		// check the library is one to include
+		if !spc.SPsite.Config.includeLibrary(spc.DocumentLibraries[i].Name) {
+			if spc.Debug {
+				fmt.Printf("CatalogContents - Skipping Document Library-%v in %v\n", spc.DocumentLibraries[i].Name, spc.DocumentLibraries[i].SiteURL)
+			}
 			continue
 		}
 
 		// Create a pointer to sps so the iterated Document library is updated
 		pdl = &spc.DocumentLibraries[i]
 
-		fmt.Printf("CatalogContents for Document Library-%v\n", pdl.Name)
+		fmt.Printf("CatalogContents for Document Library-%v in %v\n", pdl.Name, pdl.SiteURL)
 
 		// Catalog the contents (files and folders) in the document library
 		err := pdl.catalogDocumentLibraryContents(spc)
@@ -226,6 +258,7 @@ func (spc *SharePointColector) CatalogContents() error {
 		for _, file := range pdl.LibraryFiles {
 			dlFiles = append(dlFiles, file)
 		}
+		dlFiles = append(dlFiles, pdl.DeletedFiles...)
 
 		// Append slice of files to all the files
 		spc.AllLibraryFiles = append(spc.AllLibraryFiles, dlFiles...)
@@ -242,11 +275,10 @@ func (spc *SharePointColector) CatalogContents() error {
 // get Site configuration
 func (sps *SharePointSite) SiteConfig() error {
 	c := sps.Config
-	spAPI := sps.spAPI
 
 	// Get the Site Title
 	// res, err := sp.Web().Select("Title").Get()
-	res, err := spAPI.Web().Get()
+	res, err := sps.web.Get()
 	if err != nil {
 		return fmt.Errorf("GetSiteName - unable to get title: %v", err)
 	}
@@ -266,33 +298,79 @@ func (sps *SharePointSite) SiteConfig() error {
 
 }
 
-// SPdocumentLibaries - list all the document libraries in a SharePoint site
-func (spc *SharePointColector) UpdateDocumentLibaries() error {
-	sps := spc.SPsite
+// UpdateSubSites - list the subsites of a SharePoint site and recurse into theirs
+// until the subsite depth in the config is reached
+func (spc *SharePointColector) UpdateSubSites(sps *SharePointSite) error {
 	c := sps.Config
 
-	// Get the libaraies in the SharePoint site
-	// This code retrieves only the lists of type "DocumentLibrary" by including a filter that checks the `BaseTemplate` property of each list.
-	// The `101` value corresponds to the "Document Library" template in SharePoint.
-	// libraries, err := sp.Web().Lists().Filter("BaseTemplate eq 101").Get()
-	libraries, err := sps.spAPI.Web().Lists().Filter("BaseTemplate eq 101").Get()
+	if sps.Level >= spc.SPsubSiteDepth {
+		return nil
+	}
+
+	webs, err := sps.web.Webs().Select("Title,Url").Get()
 	if err != nil {
-		return fmt.Errorf("DocumentLibaries - unable to get lists: %v", err)
+		return fmt.Errorf("UpdateSubSites - unable to get subsites of %v: %v", sps.SiteURL, err)
 	}
 
-	// Work through the libraries and add them to the SharePointSite struct
-	for _, list := range libraries.Data() {
-		var dl DocumentLibrary
-		foo := list.Data()
+	for _, web := range webs.Data() {
+		info := web.Data()
 
-		dl.ID = foo.ID
-		dl.Name = foo.Title
-		dl.ParentWebURL = foo.ParentWebURL
-		dl.Path = getLibraryPath(foo.ParentWebURL, foo.DocumentTemplateURL, foo.Title)
-		spc.DocumentLibraries = append(spc.DocumentLibraries, dl)
+		subSite := &SharePointSite{
+			SiteName: info.Title,
+			SiteURL:  info.URL,
+			Level:    sps.Level + 1,
+			spAPI:    sps.spAPI,
+			client:   sps.client,
+			web:      sps.web.FromURL(info.URL + "/_api/Web"),
+			Config:   c,
+		}
+		spc.SubSites = append(spc.SubSites, subSite)
 
 		if c.Debug {
-			utils.PrintPrettyStructDebug(dl)
+			fmt.Printf("UpdateSubSites - Level:%v SubSite:%v URL:%v\n", subSite.Level, subSite.SiteName, subSite.SiteURL)
+		}
+
+		err = spc.UpdateSubSites(subSite)
+		if err != nil {
+			return err
+		}
+	}
+
+	return err
+}
+
+// SPdocumentLibaries - list all the document libraries in a SharePoint site and its subsites
+func (spc *SharePointColector) UpdateDocumentLibaries() error {
+	var err error
+	c := spc.SPsite.Config
+
+	sites := append([]*SharePointSite{&spc.SPsite}, spc.SubSites...)
+	for _, sps := range sites {
+		// Get the libaraies in the SharePoint site
+		// This code retrieves only the lists of type "DocumentLibrary" by including a filter that checks the `BaseTemplate` property of each list.
+		// The `101` value corresponds to the "Document Library" template in SharePoint.
+		// libraries, err := sp.Web().Lists().Filter("BaseTemplate eq 101").Get()
+		libraries, err := sps.web.Lists().Filter("BaseTemplate eq 101").Get()
+		if err != nil {
+			return fmt.Errorf("DocumentLibaries - unable to get lists in %v: %v", sps.SiteURL, err)
+		}
+
+		// Work through the libraries and add them to the SharePointSite struct
+		for _, list := range libraries.Data() {
+			var dl DocumentLibrary
+			foo := list.Data()
+
+			dl.ID = foo.ID
+			dl.Name = foo.Title
+			dl.ParentWebURL = foo.ParentWebURL
+			dl.SiteURL = sps.SiteURL
+			dl.Path = getLibraryPath(foo.ParentWebURL, foo.DocumentTemplateURL, foo.Title)
+			dl.site = sps
+			spc.DocumentLibraries = append(spc.DocumentLibraries, dl)
+
+			if c.Debug {
+				utils.PrintPrettyStructDebug(dl)
+			}
 		}
 	}
 
@@ -321,12 +399,17 @@ func getLibraryPath(siteURL string, fURL string, title string) string {
 func (dl *DocumentLibrary) catalogDocumentLibraryContents(spc *SharePointColector) error {
 
 	var root Folder
-	sps := spc.SPsite
-	c := sps.Config
+	c := dl.site.Config
 	libFiles := make(LibraryFiles)
 	dl.LibraryFiles = libFiles
+	dl.DeletedFiles = nil
 
-	err := getFilesAndFolders(c, sps.spAPI, dl, &root, 0)
+	// Only the changes since the last run
+	if c.SPstateDir != "" {
+		return dl.syncDocumentLibraryContents()
+	}
+
+	err := getFilesAndFolders(c, dl.site.web, dl, &root, 0)
 	if err != nil {
 		return fmt.Errorf("LibraryContents - Error occured get files and folders: %v", err)
 	}
@@ -338,7 +421,7 @@ func (dl *DocumentLibrary) catalogDocumentLibraryContents(spc *SharePointColecto
 }
 
 // getFilesAndFolders - Allow the recursion to get the files and folders in a SharePoint library
-func getFilesAndFolders(c *SharePointConfig, sp *api.SP, dl *DocumentLibrary, folder *Folder, level int) error {
+func getFilesAndFolders(c *SharePointConfig, web *api.Web, dl *DocumentLibrary, folder *Folder, level int) error {
 	var err error
 	var self api.FolderResp
 	path := dl.Path
@@ -346,11 +429,11 @@ func getFilesAndFolders(c *SharePointConfig, sp *api.SP, dl *DocumentLibrary, fo
 	// Get self folder details to determine the folder UniqueID
 	// The initial level will not have got a UID yet so use the path
 	if level == 0 {
-		self, err = sp.Web().GetFolder(path).Get()
+		self, err = web.GetFolder(path).Get()
 
 		// for all other levels use the UID to avoid path length issues
 	} else {
-		self, err = sp.Web().GetFolderByID(folder.UniqueID).Get()
+		self, err = web.GetFolderByID(folder.UniqueID).Get()
 	}
 	if err != nil {
 		return fmt.Errorf("getFilesFolders - Can't get self in path:%v - error:%v", path, err)
@@ -364,13 +447,13 @@ func getFilesAndFolders(c *SharePointConfig, sp *api.SP, dl *DocumentLibrary, fo
 	folder.UniqueID = selfUID
 
 	// Internal helps to get the folders in the Library
-	err = getFolders(c, sp, path, folder, dl, level)
+	err = getFolders(c, web, path, folder, dl, level)
 	if err != nil {
 		return fmt.Errorf("LibraryContents - collect Folder in %v - error:%v", path, err)
 	}
 
 	// Get the files in the folder
-	err = getFiles(c, sp, folder, dl)
+	err = getFiles(c, web, folder, dl)
 	if err != nil {
 		return fmt.Errorf("LibraryContents - collect Files in %v - error:%v", path, err)
 	}
@@ -379,10 +462,10 @@ func getFilesAndFolders(c *SharePointConfig, sp *api.SP, dl *DocumentLibrary, fo
 
 }
 
-func getFolders(c *SharePointConfig, sp *api.SP, path string, folder *Folder, dl *DocumentLibrary, level int) error {
+func getFolders(c *SharePointConfig, web *api.Web, path string, folder *Folder, dl *DocumentLibrary, level int) error {
 
 	// Not Using GetFolderByPath() as this hits path length issues
-	spFolders, err := sp.Web().GetFolderByID(folder.UniqueID).Folders().Get()
+	spFolders, err := web.GetFolderByID(folder.UniqueID).Folders().Get()
 	if err != nil {
 		return fmt.Errorf("getFolders - Error getting files:%v", err)
 	}
@@ -412,17 +495,13 @@ func getFolders(c *SharePointConfig, sp *api.SP, path string, folder *Folder, dl
 			fmt.Printf("getFolders-Level:%v-Recurse into folder:%v-path:%v\n", i, spFolder.Data().Name, spFolder.Data().ServerRelativeURL)
 		}
 
-		// Increment level to for the recursion to track the depth
-		level++
-		// limit the depth of the recursion
-		if c.SPdepthLimit != 0 {
-			if level > c.SPdepthLimit {
-				continue
-			}
+		// The folder is a level below its parent, limit the depth of the recursion
+		if !c.includeDepth(level + 1) {
+			continue
 		}
 
 		// Recurse the files and folders to drill down into this loops folder
-		err = getFilesAndFolders(c, sp, dl, &internalFolder, level)
+		err = getFilesAndFolders(c, web, dl, &internalFolder, level+1)
 		if err != nil {
 			return fmt.Errorf("getFolders - Recurse Error occured get files and folders: %v", err)
 		}
@@ -435,13 +514,22 @@ func getFolders(c *SharePointConfig, sp *api.SP, path string, folder *Folder, dl
 }
 
 // getFiles - list all the files in a SharePoint library Folder
-func getFiles(c *SharePointConfig, sp *api.SP, folder *Folder, dl *DocumentLibrary) error {
+func getFiles(c *SharePointConfig, web *api.Web, folder *Folder, dl *DocumentLibrary) error {
 
 	var files []File
 	var err error
 
 	// TODO - Add handling of 403 errors give a warning not a fatal error
-	spFiles, err := sp.Web().GetFolderByID(folder.UniqueID).Files().Get()
+	// Only list the files that pass the filters, with the list item fields for the filters and columns
+	query := web.GetFolderByID(folder.UniqueID).Files()
+	if filter := c.filesFilter(); filter != "" {
+		query = query.Filter(filter)
+	}
+	if expand := c.filesExpand(); expand != "" {
+		query = query.Expand(expand)
+	}
+
+	spFiles, err := query.Get()
 	if err != nil {
 		return fmt.Errorf("LibraryContents Error getting files error:%v", err)
 	}
@@ -459,8 +547,12 @@ func getFiles(c *SharePointConfig, sp *api.SP, folder *Folder, dl *DocumentLibra
 		// TODO
 
 		file := mapFileValues(&spFile)
+		file.Columns = c.fileColumns(api.NormalizeODataItem(spFile))
+		file.Permissions = c.filePermissions(api.NormalizeODataItem(spFile))
 
 		file.I = i
+		file.DocumentLibrary = dl.Name
+		file.SiteURL = dl.SiteURL
 
 		// Append file details to to the list of files
 		files = append(files, file)
@@ -542,7 +634,13 @@ func (spc *SharePointColector) DownloadContentData(ff interface{}) (*[]byte, err
 		return nil, fmt.Errorf("\n\tDownloadLibraryFile - File ID is blank not found in library for file:%v", file.Name)
 	}
 
-	data, err := spAPI.Web().GetFileByID(file.UniqueID).Download()
+	// Files in a subsite are downloaded through the subsite's Web
+	web := spAPI.Web()
+	if file.SiteURL != "" && file.SiteURL != sps.SiteURL {
+		web = web.FromURL(file.SiteURL + "/_api/Web")
+	}
+
+	data, err := web.GetFileByID(file.UniqueID).Download()
 	if err != nil {
 		return nil, fmt.Errorf("DownloadLibraryFile - Error downloading file:%v", err)
 	}
@@ -610,53 +708,6 @@ func FilterFilePath(c *SharePointConfig, filePath string) bool {
 	return false
 }
 
-func ListsubSites(c *SharePointConfig) {
-
-	/*
-		spAPI, err := setupAPI(c)
-		if err != nil {
-			fmt.Println("ListSPsubSites - Error setting up API:", err)
-			return
-		}
-
-		// Get the subsites in the SharePoint site
-		subsites, err := spAPI.Webs().Select("Title").Get()
-		if err != nil {
-			fmt.Println("Error getting subsites:", err)
-			return
-		}
-
-		// Print the titles of all the subsites
-		for _, subsite := range subsites.Data() {
-			fmt.Println(subsite.Data().Title)
-		}
-
-	*/
-
-}
-
-func SubSiteName(c *SharePointConfig) {
-
-	/*
-		var err error
-
-		spAPI, err := setupAPI(c)
-		if err != nil {
-			fmt.Println("ListSPsubSites - Error setting up API:", err)
-			return
-		}
-
-		// res, err := spAPI.Web().Select("Title").Get()
-		res, err := spAPI.Webs().Select("Title").Get()
-		if err != nil {
-			log.Fatalf("GetFilesSharepoint - unable to get title: %v", err)
-		}
-
-		fmt.Printf("Site Name=%v", res.Data())
-	*/
-
-}
-
 // Setup hook handlers for the api
 func setupHookHandlers(c *SharePointColector, client *gosip.SPClient) {
 	// Define requests hook handlers
@@ -784,107 +835,29 @@ func (file *File) GetPathHash() string {
 
 }
 
-// Legacy SubSite Code
-/*
-
-type mockSubSite struct {
-	Name string
-	URL  string
+func (file *File) GetParentLocation() string {
+	return filepath.Dir(file.ServerRelativeURL)
 }
 
-// ListSubSitesMock - write a function which opens a text file (parameter to the function) and reads the contents
-// the contents are a list of SharePoint sub sites URLS
-// each URL needs the string /Forms/AllItems.aspx removing from the end
-// The is needs to be added as a SubSite struct as a slice to the SharePointSite struct field SubSites
-func (sps *SharePointSite) GetSubSitesMock(filePath string) error {
-
-	var mss []mockSubSite
-
-	//
-	if filePath == "" {
-		return fmt.Errorf("GetSubSitesMock - No file path provided")
-	}
-
-	// Open the CSV file defined by filePath and read line by file
-	file, err := os.Open(filePath)
-	if err != nil {
-		return fmt.Errorf("GetSubSitesMockError opening file: %v", err)
-	}
-	defer file.Close()
-
-	// Create a new CSV reader
-	reader := csv.NewReader(file)
-
-	// Read the CSV records
-	for {
-		record, err := reader.Read()
-		if err == io.EOF {
-			break
-		}
-		if err != nil {
-			fmt.Printf("Error reading record: %v\n", err)
-			continue
-		}
-
-		// Skip blank URL Values
-		if record[17] == "" {
-			continue
-		}
-
-		mss = append(mss, mockSubSite{Name: record[4], URL: record[17]})
-	}
-
-	// Remove the /Forms/AllItems.aspx suffix from each URL
-	var subSites []*SharePointSite
-	for _, ss := range mss {
-
-		subSite := SharePointSite{
-			SiteURL:  strings.TrimSuffix(ss.URL, "/Forms/AllItems.aspx"),
-			SiteName: ss.Name,
-			spAPI:    sps.spAPI,
-			// isSubSite: true
-		}
-		// Name: res.Data().Title,
-		// Name: res.Name,
-		// ID:   res.ID
-		// subSites = append(subSites, &subSite)
-
-	}
-
-	// Add the data to the sharepoint site
-	sps.SubSites = subSites
-
-	return err
+func (file *File) GetTimeLastModified() time.Time {
+	return file.TimeLastModified
 }
 
-// ListSubSites - list all the sub sites in a SharePoint site
-func (sps *SharePointSite) GetSubSites() error {
-	spAPI := sps.spAPI
-
-	// if err != nil {
-	// 	return fmt.Errorf("GetSiteName - unable to setup SP connection: %v", err)
-	// }
-
-	// Get the Site Title
-	// res, err := sp.Web().Select("Title").Get()
-	res, err := spAPI.Web().Get()
-	if err != nil {
-		return fmt.Errorf("ListSubSites - GetSiteName - unable to get title: %v", err)
-	}
-
-	res.Data().Title = sps.SiteName
-
-	webs, err := spAPI.Web().Webs().Get()
-	if err != nil {
-		return fmt.Errorf("ListSubSites - unable to get subsites: %v", err)
-	}
-
-	foo := webs.Data()
-
-	utils.PrintPrettyStructDebug(foo)
+func (file *File) GetSize() int64 {
+	return int64(file.Length)
+}
 
-	return err
+// GetMetaData - The list item columns of the file
+func (file *File) GetMetaData() map[string]string {
+	return file.Columns
+}
 
+// GetPermissions - The users and groups that can read the file
+func (file *File) GetPermissions() []models.ContentPermission {
+	return file.Permissions
 }
 
-*/
+// IsDeleted - The file has been deleted since the last run
+func (file *File) IsDeleted() bool {
+	return file.Deleted
+}
@@ -0,0 +1,322 @@
+package website
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/cookiejar"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/PuerkitoBio/goquery"
+	"github.com/joho/godotenv"
+)
+
+// Keys in the auth SecretsFile, a .env style file
+const (
+	SecretBearerToken = "BEARER_TOKEN"
+	SecretUsername    = "USERNAME"
+	SecretPassword    = "PASSWORD"
+)
+
+// AuthConfig - How the crawler signs in to an intranet or portal
+// The credentials are kept in the SecretsFile rather than the config
+type AuthConfig struct {
+	// .env style file with the BEARER_TOKEN, USERNAME and PASSWORD
+	// and any other values used in the Headers
+	SecretsFile string
+	// Headers sent with every request, ${NAME} is replaced with NAME from the SecretsFile
+	Headers map[string]string
+	// Cookies exported from a browser in the Netscape cookies.txt format
+	CookieFile string
+	// The login form is posted before the crawl and again when a page is 401 Unauthorized
+	// Hidden fields on the form e.g. CSRF tokens are posted with the credentials and LoginFields
+	LoginURL      string
+	UsernameField string
+	PasswordField string
+	LoginFields   map[string]string
+}
+
+// IsSet - There is some auth to use
+func (ac AuthConfig) IsSet() bool {
+	return ac.SecretsFile != "" || len(ac.Headers) > 0 || ac.CookieFile != "" || ac.LoginURL != ""
+}
+
+// authenticator - Signs the crawler in and keeps the session for colly and the sitemap requests
+type authenticator struct {
+	config    AuthConfig
+	userAgent string
+	debug     bool
+	jar       *cookiejar.Jar
+	client    *http.Client
+
+	mu       sync.Mutex
+	headers  http.Header
+	username string
+	password string
+	// session - Counts the logins so a 401 for a request sent before the last one isn't signed in again
+	session int
+	// refreshMu - One refresh at a time
+	refreshMu sync.Mutex
+}
+
+func newAuthenticator(c *WebsiteConfig) (*authenticator, error) {
+	jar, err := cookiejar.New(nil)
+	if err != nil {
+		return nil, fmt.Errorf("newAuthenticator - %v", err)
+	}
+
+	a := authenticator{
+		config:    c.Auth,
+		userAgent: c.UserAgent,
+		debug:     c.Debug,
+		jar:       jar,
+		client:    &http.Client{Jar: jar, Timeout: 30 * time.Second},
+	}
+
+	err = a.readSecrets()
+	if err != nil {
+		return nil, err
+	}
+
+	if c.Auth.CookieFile != "" {
+		err = a.importCookies(c.Auth.CookieFile)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return &a, nil
+}
+
+// readSecrets - Read the SecretsFile and set the headers from it
+// It's read again on refresh so a rotated token is picked up
+func (a *authenticator) readSecrets() error {
+	secrets := make(map[string]string)
+	if a.config.SecretsFile != "" {
+		var err error
+		secrets, err = godotenv.Read(a.config.SecretsFile)
+		if err != nil {
+			return fmt.Errorf("readSecrets - unable to read SecretsFile:%v - %v", a.config.SecretsFile, err)
+		}
+	}
+
+	headers := make(http.Header)
+	for name, value := range a.config.Headers {
+		headers.Set(name, os.Expand(value, func(key string) string { return secrets[key] }))
+	}
+	if token := secrets[SecretBearerToken]; token != "" {
+		headers.Set("Authorization", "Bearer "+token)
+	}
+
+	if a.config.LoginURL != "" && (secrets[SecretUsername] == "" || secrets[SecretPassword] == "") {
+		return fmt.Errorf("readSecrets - %v and %v are required in the SecretsFile for the LoginURL", SecretUsername, SecretPassword)
+	}
+
+	a.mu.Lock()
+	a.headers = headers
+	a.username = secrets[SecretUsername]
+	a.password = secrets[SecretPassword]
+	a.mu.Unlock()
+
+	return nil
+}
+
+// setHeaders - Add the auth headers to a request, returns the session they are for
+func (a *authenticator) setHeaders(h http.Header) int {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	for name, values := range a.headers {
+		h[name] = values
+	}
+	// The cookies come from the jar, a retried request would send the old session too
+	h.Del("Cookie")
+
+	return a.session
+}
+
+// refresh - Read the secrets again and log in again after a request in the session got a 401
+func (a *authenticator) refresh(session int) error {
+	a.refreshMu.Lock()
+	defer a.refreshMu.Unlock()
+
+	// Another page has signed in again since the request was sent
+	a.mu.Lock()
+	current := a.session
+	a.mu.Unlock()
+	if current != session {
+		return nil
+	}
+
+	err := a.readSecrets()
+	if err != nil {
+		return err
+	}
+
+	if a.config.LoginURL != "" {
+		return a.login()
+	}
+
+	a.mu.Lock()
+	a.session++
+	a.mu.Unlock()
+	return nil
+}
+
+// login - Post the login form with the credentials
+// The form is fetched first for its hidden fields and the session cookie
+func (a *authenticator) login() error {
+	a.mu.Lock()
+	username, password := a.username, a.password
+	a.mu.Unlock()
+
+	if a.debug {
+		fmt.Printf("login - DEBUG - Logging in at:%v\n", a.config.LoginURL)
+	}
+
+	page, body, err := a.get(a.config.LoginURL)
+	if err != nil {
+		return fmt.Errorf("login - %v", err)
+	}
+
+	doc, err := goquery.NewDocumentFromReader(bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("login - %v", err)
+	}
+
+	// The form with the password field
+	form := doc.Find("form").FilterFunction(func(i int, s *goquery.Selection) bool {
+		return s.Find(fmt.Sprintf("input[name=%q]", a.config.PasswordField)).Length() > 0
+	}).First()
+
+	values := url.Values{}
+	action := page
+	if form.Length() > 0 {
+		form.Find("input[type=hidden][name]").Each(func(i int, s *goquery.Selection) {
+			values.Set(s.AttrOr("name", ""), s.AttrOr("value", ""))
+		})
+		if act := form.AttrOr("action", ""); act != "" {
+			if au, err := page.Parse(act); err == nil {
+				action = au
+			}
+		}
+	}
+
+	for name, value := range a.config.LoginFields {
+		values.Set(name, value)
+	}
+	values.Set(a.config.UsernameField, username)
+	values.Set(a.config.PasswordField, password)
+
+	req, err := http.NewRequest("POST", action.String(), strings.NewReader(values.Encode()))
+	if err != nil {
+		return fmt.Errorf("login - %v", err)
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("User-Agent", a.userAgent)
+
+	resp, err := a.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("login - %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 400 {
+		return fmt.Errorf("login - %v - status:%v", action, resp.Status)
+	}
+
+	// Back at the login form so the credentials were wrong
+	after, err := goquery.NewDocumentFromReader(resp.Body)
+	if err == nil && after.Find(fmt.Sprintf("form input[name=%q]", a.config.PasswordField)).Length() > 0 {
+		return fmt.Errorf("login - %v - still on the login form after posting the credentials", action)
+	}
+
+	a.mu.Lock()
+	a.session++
+	a.mu.Unlock()
+
+	return nil
+}
+
+// get - GET the URL with the session, returns the URL after redirects
+func (a *authenticator) get(u string) (*url.URL, []byte, error) {
+	req, err := http.NewRequest("GET", u, nil)
+	if err != nil {
+		return nil, nil, err
+	}
+	req.Header.Set("User-Agent", a.userAgent)
+	a.setHeaders(req.Header)
+
+	resp, err := a.client.Do(req)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 400 {
+		return nil, nil, fmt.Errorf("get - %v - status:%v", u, resp.Status)
+	}
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 10<<20))
+	return resp.Request.URL, body, err
+}
+
+// importCookies - Add the cookies from a Netscape cookies.txt file to the jar
+// domain, include subdomains, path, secure, expiry, name and value separated by tabs
+func (a *authenticator) importCookies(name string) error {
+	f, err := os.Open(name)
+	if err != nil {
+		return fmt.Errorf("importCookies - %v", err)
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		httpOnly := strings.HasPrefix(line, "#HttpOnly_")
+		line = strings.TrimPrefix(line, "#HttpOnly_")
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		fields := strings.Split(line, "\t")
+		if len(fields) != 7 {
+			return fmt.Errorf("importCookies - %v - not a cookies.txt line:%q", name, line)
+		}
+
+		domain := strings.TrimPrefix(fields[0], ".")
+		secure := strings.EqualFold(fields[3], "TRUE")
+		cookie := &http.Cookie{
+			Name:     fields[5],
+			Value:    fields[6],
+			Path:     fields[2],
+			Secure:   secure,
+			HttpOnly: httpOnly,
+		}
+		if strings.EqualFold(fields[1], "TRUE") {
+			cookie.Domain = domain
+		}
+		// 0 is a session cookie
+		if expires, err := strconv.ParseInt(fields[4], 10, 64); err == nil && expires > 0 {
+			cookie.Expires = time.Unix(expires, 0)
+		}
+
+		scheme := "http"
+		if secure {
+			scheme = "https"
+		}
+		a.jar.SetCookies(&url.URL{Scheme: scheme, Host: domain, Path: fields[2]}, []*http.Cookie{cookie})
+	}
+
+	if err = scanner.Err(); err != nil {
+		return fmt.Errorf("importCookies - %v", err)
+	}
+	return nil
+}
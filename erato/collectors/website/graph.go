@@ -0,0 +1,216 @@
+package website
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// LinkGraph - The links between the pages of the site
+type LinkGraph struct {
+	Site  string
+	Nodes []GraphNode
+	Edges []GraphEdge
+}
+
+// GraphNode - A page or a link to a page in the allowed domains
+type GraphNode struct {
+	URL string
+	// Fewest links from the SiteURL, -1 when it can't be reached by following links
+	Depth     int
+	InDegree  int
+	OutDegree int
+	// The page is in the collection, links that weren't visited e.g. past the MaxDepth are not
+	Collected bool
+	// No page links to it e.g. it was only found in the sitemap
+	Orphan bool
+}
+
+// GraphEdge - A link from one page to another
+type GraphEdge struct {
+	From string
+	To   string
+}
+
+// recordLink - Remember the link for the page's Links and the link graph
+func (w *WebsiteCollector) recordLink(from string, to string) {
+	if from == to {
+		return
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.links[from] == nil {
+		w.links[from] = make(map[string]bool)
+	}
+	w.links[from][to] = true
+}
+
+// pageLinks - The links found on the page sorted
+func (w *WebsiteCollector) pageLinks(u string) []string {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	var links []string
+	for l := range w.links[u] {
+		links = append(links, l)
+	}
+	sort.Strings(links)
+	return links
+}
+
+// LinkGraph - Build the link graph from the Links of the pages in the collection
+func (w *WebsiteCollector) LinkGraph() LinkGraph {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	root := w.urls.normalise(w.SiteURL)
+	g := LinkGraph{Site: root}
+
+	// A link to a URL that was catalogued at its canonical URL is a link to the canonical URL
+	resolve := func(u string) string {
+		if c, ok := w.canonicals[u]; ok {
+			return c
+		}
+		return u
+	}
+
+	nodes := make(map[string]*GraphNode)
+	node := func(u string) *GraphNode {
+		n, ok := nodes[u]
+		if !ok {
+			n = &GraphNode{URL: u, Depth: -1}
+			nodes[u] = n
+		}
+		return n
+	}
+
+	seen := make(map[GraphEdge]bool)
+	out := make(map[string][]string)
+	for _, p := range w.AllSitePages {
+		node(p.URL).Collected = true
+		for _, l := range p.Links {
+			e := GraphEdge{From: p.URL, To: resolve(l)}
+			if e.From == e.To || seen[e] {
+				continue
+			}
+			seen[e] = true
+			g.Edges = append(g.Edges, e)
+			out[e.From] = append(out[e.From], e.To)
+			node(e.From).OutDegree++
+			node(e.To).InDegree++
+		}
+	}
+
+	// Breadth first from the SiteURL for the depth of each page
+	if _, ok := nodes[root]; ok {
+		nodes[root].Depth = 0
+		queue := []string{root}
+		for len(queue) > 0 {
+			u := queue[0]
+			queue = queue[1:]
+			for _, to := range out[u] {
+				if nodes[to].Depth == -1 {
+					nodes[to].Depth = nodes[u].Depth + 1
+					queue = append(queue, to)
+				}
+			}
+		}
+	}
+
+	for _, n := range nodes {
+		n.Orphan = n.InDegree == 0 && n.URL != root
+		g.Nodes = append(g.Nodes, *n)
+	}
+	sort.Slice(g.Nodes, func(i, j int) bool { return g.Nodes[i].URL < g.Nodes[j].URL })
+	sort.Slice(g.Edges, func(i, j int) bool {
+		if g.Edges[i].From != g.Edges[j].From {
+			return g.Edges[i].From < g.Edges[j].From
+		}
+		return g.Edges[i].To < g.Edges[j].To
+	})
+
+	return g
+}
+
+// ExportLinkGraph - Write the link graph as JSON, GraphML and DOT files in the directory
+// The files are named after the collection e.g. NHS-linkgraph.json
+func (w *WebsiteCollector) ExportLinkGraph(dir string, name string) error {
+	g := w.LinkGraph()
+	base := filepath.Join(dir, strings.ReplaceAll(name, " ", "_")+"-linkgraph")
+
+	data, err := json.MarshalIndent(g, "", "\t")
+	if err != nil {
+		return fmt.Errorf("ExportLinkGraph - %v", err)
+	}
+
+	for ext, data := range map[string][]byte{".json": data, ".graphml": g.GraphML(), ".dot": g.DOT()} {
+		err = os.WriteFile(base+ext, data, 0644)
+		if err != nil {
+			return fmt.Errorf("ExportLinkGraph - %v", err)
+		}
+	}
+
+	return nil
+}
+
+// GraphML - The graph for tools such as Gephi and yEd
+func (g LinkGraph) GraphML() []byte {
+	var sb strings.Builder
+
+	sb.WriteString(xml.Header)
+	sb.WriteString(`<graphml xmlns="http://graphml.graphdrawing.org/xmlns">` + "\n")
+	sb.WriteString(`  <key id="depth" for="node" attr.name="depth" attr.type="int"/>` + "\n")
+	sb.WriteString(`  <key id="indegree" for="node" attr.name="indegree" attr.type="int"/>` + "\n")
+	sb.WriteString(`  <key id="outdegree" for="node" attr.name="outdegree" attr.type="int"/>` + "\n")
+	sb.WriteString(`  <key id="collected" for="node" attr.name="collected" attr.type="boolean"/>` + "\n")
+	sb.WriteString(`  <key id="orphan" for="node" attr.name="orphan" attr.type="boolean"/>` + "\n")
+	sb.WriteString(`  <graph id="` + xmlEscape(g.Site) + `" edgedefault="directed">` + "\n")
+
+	for _, n := range g.Nodes {
+		fmt.Fprintf(&sb, `    <node id="%v">`+"\n", xmlEscape(n.URL))
+		fmt.Fprintf(&sb, `      <data key="depth">%v</data>`+"\n", n.Depth)
+		fmt.Fprintf(&sb, `      <data key="indegree">%v</data>`+"\n", n.InDegree)
+		fmt.Fprintf(&sb, `      <data key="outdegree">%v</data>`+"\n", n.OutDegree)
+		fmt.Fprintf(&sb, `      <data key="collected">%v</data>`+"\n", n.Collected)
+		fmt.Fprintf(&sb, `      <data key="orphan">%v</data>`+"\n", n.Orphan)
+		sb.WriteString("    </node>\n")
+	}
+
+	for _, e := range g.Edges {
+		fmt.Fprintf(&sb, `    <edge source="%v" target="%v"/>`+"\n", xmlEscape(e.From), xmlEscape(e.To))
+	}
+
+	sb.WriteString("  </graph>\n</graphml>\n")
+
+	return []byte(sb.String())
+}
+
+// DOT - The graph for Graphviz
+func (g LinkGraph) DOT() []byte {
+	var sb strings.Builder
+
+	sb.WriteString("digraph site {\n")
+	for _, n := range g.Nodes {
+		fmt.Fprintf(&sb, "  %v [depth=%v, indegree=%v, outdegree=%v, collected=%v, orphan=%v];\n",
+			strconv.Quote(n.URL), n.Depth, n.InDegree, n.OutDegree, n.Collected, n.Orphan)
+	}
+	for _, e := range g.Edges {
+		fmt.Fprintf(&sb, "  %v -> %v;\n", strconv.Quote(e.From), strconv.Quote(e.To))
+	}
+	sb.WriteString("}\n")
+
+	return []byte(sb.String())
+}
+
+func xmlEscape(s string) string {
+	var sb strings.Builder
+	xml.EscapeText(&sb, []byte(s))
+	return sb.String()
+}
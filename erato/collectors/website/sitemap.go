@@ -0,0 +1,185 @@
+package website
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// The deepest a sitemap index can nest other sitemap indexes
+const sitemapMaxDepth = 3
+
+// Sitemaps over this size are not read (the protocol limit is 50MB uncompressed)
+const sitemapMaxSize = 50 << 20
+
+// SitemapURL - A page listed in a sitemap
+type SitemapURL struct {
+	Loc     string
+	LastMod time.Time
+}
+
+// sitemapXML - Both a urlset and a sitemapindex
+type sitemapXML struct {
+	XMLName xml.Name
+	URLs    []struct {
+		Loc     string `xml:"loc"`
+		LastMod string `xml:"lastmod"`
+	} `xml:"url"`
+	Sitemaps []struct {
+		Loc string `xml:"loc"`
+	} `xml:"sitemap"`
+}
+
+// sitemapURLs - The pages in the sitemaps for the site
+// The sitemaps are the ones in the config, those listed in robots.txt and /sitemap.xml
+func (w *WebsiteCollector) sitemapURLs() ([]SitemapURL, error) {
+	var urls []SitemapURL
+
+	site, err := url.Parse(w.SiteURL)
+	if err != nil {
+		return nil, err
+	}
+	root := site.Scheme + "://" + site.Host
+
+	sitemaps := append([]string{}, w.Config.Sitemaps...)
+	if len(sitemaps) == 0 {
+		sitemaps = append(sitemaps, w.robotsSitemaps(root)...)
+	}
+	if len(sitemaps) == 0 {
+		sitemaps = append(sitemaps, root+"/sitemap.xml")
+	}
+
+	seen := make(map[string]bool)
+	for _, sm := range sitemaps {
+		found, err := w.readSitemap(sm, 0, seen)
+		if err != nil {
+			if w.Config.Debug {
+				fmt.Printf("sitemapURLs - DEBUG - Sitemap:%v - %v\n", sm, err)
+			}
+			continue
+		}
+		urls = append(urls, found...)
+	}
+
+	return urls, nil
+}
+
+// robotsSitemaps - The Sitemap: lines in the site's robots.txt
+func (w *WebsiteCollector) robotsSitemaps(root string) []string {
+	var sitemaps []string
+
+	data, err := w.fetch(root + "/robots.txt")
+	if err != nil {
+		return sitemaps
+	}
+
+	for _, line := range strings.Split(string(data), "\n") {
+		line = strings.TrimSpace(line)
+		if len(line) > 8 && strings.EqualFold(line[:8], "sitemap:") {
+			sitemaps = append(sitemaps, strings.TrimSpace(line[8:]))
+		}
+	}
+
+	return sitemaps
+}
+
+// readSitemap - The pages in a sitemap, following the sitemaps in a sitemap index
+func (w *WebsiteCollector) readSitemap(sitemapURL string, depth int, seen map[string]bool) ([]SitemapURL, error) {
+	var urls []SitemapURL
+
+	if seen[sitemapURL] || depth > sitemapMaxDepth {
+		return urls, nil
+	}
+	seen[sitemapURL] = true
+
+	data, err := w.fetch(sitemapURL)
+	if err != nil {
+		return nil, err
+	}
+
+	var sm sitemapXML
+	err = xml.Unmarshal(data, &sm)
+	if err != nil {
+		return nil, fmt.Errorf("readSitemap - %v", err)
+	}
+
+	for _, u := range sm.URLs {
+		loc := strings.TrimSpace(u.Loc)
+		if loc == "" {
+			continue
+		}
+		urls = append(urls, SitemapURL{Loc: loc, LastMod: parseLastMod(u.LastMod)})
+	}
+
+	for _, s := range sm.Sitemaps {
+		found, err := w.readSitemap(strings.TrimSpace(s.Loc), depth+1, seen)
+		if err != nil {
+			if w.Config.Debug {
+				fmt.Printf("readSitemap - DEBUG - Sitemap:%v - %v\n", s.Loc, err)
+			}
+			continue
+		}
+		urls = append(urls, found...)
+	}
+
+	return urls, nil
+}
+
+// fetch - GET the URL with the collector's user agent, gzipped files are unzipped
+func (w *WebsiteCollector) fetch(u string) ([]byte, error) {
+	req, err := http.NewRequest("GET", u, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("User-Agent", w.Config.UserAgent)
+
+	client := &http.Client{Timeout: 30 * time.Second}
+	// Sitemaps behind the login are fetched with the session
+	if w.auth != nil {
+		w.auth.setHeaders(req.Header)
+		client = w.auth.client
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("fetch - %v - status:%v", u, resp.Status)
+	}
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, sitemapMaxSize))
+	if err != nil {
+		return nil, err
+	}
+
+	// sitemap.xml.gz
+	if bytes.HasPrefix(data, []byte{0x1f, 0x8b}) {
+		zr, err := gzip.NewReader(bytes.NewReader(data))
+		if err != nil {
+			return nil, err
+		}
+		defer zr.Close()
+		return io.ReadAll(io.LimitReader(zr, sitemapMaxSize))
+	}
+
+	return data, nil
+}
+
+// parseLastMod - The W3C datetime formats allowed in lastmod, zero if it doesn't parse
+func parseLastMod(s string) time.Time {
+	s = strings.TrimSpace(s)
+	for _, layout := range []string{time.RFC3339, "2006-01-02T15:04Z07:00", "2006-01-02"} {
+		if t, err := time.Parse(layout, s); err == nil {
+			return t
+		}
+	}
+	return time.Time{}
+}
@@ -0,0 +1,320 @@
+package website
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// crawlStore - The state of a crawl kept on disk so an interrupted crawl can resume
+// and the bodies of the pages aren't all held in memory
+//
+//	StateDir/
+//	  pages.jsonl    - each page scraped, without its body
+//	  previous.jsonl - the pages of the last finished crawl for conditional requests
+//	  frontier.jsonl - each link queued to visit
+//	  visited.log    - each URL finished with, scraped or failed
+//	  bodies/        - the body of each page named by its UniqueID
+//	  complete       - written when the crawl's pages are analysed, the next crawl starts again
+type crawlStore struct {
+	dir      string
+	mu       sync.Mutex
+	pages    *os.File
+	frontier *os.File
+	visited  *os.File
+	// URLs in visited.log
+	done map[string]bool
+	// The pages of the last finished crawl by URL
+	previous map[string]Page
+}
+
+// frontierLink - A link queued to visit with the depth colly would visit it at
+type frontierLink struct {
+	URL       string
+	ParentURL string
+	Depth     int
+}
+
+const (
+	storePages    = "pages.jsonl"
+	storePrevious = "previous.jsonl"
+	storeFrontier = "frontier.jsonl"
+	storeVisited  = "visited.log"
+	storeBodies   = "bodies"
+	storeComplete = "complete"
+)
+
+// openCrawlStore - Open the crawl state in the directory, creating it when it doesn't exist
+// Returns the pages already scraped and the links still to visit of an interrupted crawl
+func openCrawlStore(dir string) (*crawlStore, []Page, []frontierLink, error) {
+	s := crawlStore{dir: dir, done: make(map[string]bool), previous: make(map[string]Page)}
+
+	// The last crawl finished so start a new one keeping its pages to compare against
+	if _, err := os.Stat(filepath.Join(dir, storeComplete)); err == nil {
+		err = os.Rename(filepath.Join(dir, storePages), filepath.Join(dir, storePrevious))
+		if err != nil && !os.IsNotExist(err) {
+			return nil, nil, nil, fmt.Errorf("openCrawlStore - %v", err)
+		}
+		for _, name := range []string{storeFrontier, storeVisited, storeComplete} {
+			err = os.RemoveAll(filepath.Join(dir, name))
+			if err != nil {
+				return nil, nil, nil, fmt.Errorf("openCrawlStore - %v", err)
+			}
+		}
+	}
+
+	err := os.MkdirAll(filepath.Join(dir, storeBodies), 0755)
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("openCrawlStore - %v", err)
+	}
+
+	err = readLines(filepath.Join(dir, storePrevious), func(line []byte) {
+		var p Page
+		if json.Unmarshal(line, &p) == nil {
+			s.previous[p.URL] = p
+		}
+	})
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	var pages []Page
+	err = readLines(filepath.Join(dir, storePages), func(line []byte) {
+		var p Page
+		// The last line may be cut short when the crawl was killed
+		if json.Unmarshal(line, &p) == nil {
+			pages = append(pages, p)
+		}
+	})
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	err = readLines(filepath.Join(dir, storeVisited), func(line []byte) {
+		s.done[string(line)] = true
+	})
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	for _, p := range pages {
+		s.done[p.URL] = true
+	}
+
+	var links []frontierLink
+	queued := make(map[string]bool)
+	err = readLines(filepath.Join(dir, storeFrontier), func(line []byte) {
+		var l frontierLink
+		if json.Unmarshal(line, &l) == nil && !s.done[l.URL] && !queued[l.URL] {
+			queued[l.URL] = true
+			links = append(links, l)
+		}
+	})
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	err = s.removeUnusedBodies(pages)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	s.pages, err = appendFile(filepath.Join(dir, storePages))
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	s.frontier, err = appendFile(filepath.Join(dir, storeFrontier))
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	s.visited, err = appendFile(filepath.Join(dir, storeVisited))
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	return &s, pages, links, nil
+}
+
+// isVisited - The URL was scraped or failed in this or an earlier run of the crawl
+func (s *crawlStore) isVisited(u string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.done[u]
+}
+
+// previousPage - The page from the last finished crawl when its analysis was stored
+// A page that failed analysis isn't asked for conditionally or compared so it is analysed again
+func (s *crawlStore) previousPage(u string) (Page, bool) {
+	p, ok := s.previous[u]
+	if !ok || !p.Stored {
+		return Page{}, false
+	}
+	return p, true
+}
+
+// removeUnusedBodies - Remove the bodies of pages that are in neither this crawl nor the last
+func (s *crawlStore) removeUnusedBodies(pages []Page) error {
+	used := make(map[string]bool)
+	for _, p := range pages {
+		used[filepath.Base(p.BodyFile)] = true
+	}
+	for _, p := range s.previous {
+		used[filepath.Base(p.BodyFile)] = true
+	}
+
+	entries, err := os.ReadDir(filepath.Join(s.dir, storeBodies))
+	if err != nil {
+		return fmt.Errorf("removeUnusedBodies - %v", err)
+	}
+
+	for _, e := range entries {
+		if used[e.Name()] {
+			continue
+		}
+		err = os.Remove(filepath.Join(s.dir, storeBodies, e.Name()))
+		if err != nil {
+			return fmt.Errorf("removeUnusedBodies - %v", err)
+		}
+	}
+	return nil
+}
+
+// queue - Record a link colly has queued to visit
+func (s *crawlStore) queue(l frontierLink) error {
+	return s.appendJSON(s.frontier, l)
+}
+
+// addPage - Write the body of the page to disk and record the page
+// The page is returned without its body, which is read back with Page.body
+// An unchanged page keeps the body written by the last crawl
+func (s *crawlStore) addPage(p Page) (Page, error) {
+	if !p.Unchanged || p.BodyFile == "" {
+		p.BodyFile = filepath.Join(s.dir, storeBodies, p.UniqueID)
+		p.Size = int64(len(p.BodyData))
+
+		err := os.WriteFile(p.BodyFile, p.BodyData, 0644)
+		if err != nil {
+			return p, fmt.Errorf("addPage - %v", err)
+		}
+	}
+	p.BodyData = nil
+
+	err := s.appendJSON(s.pages, p)
+	if err != nil {
+		return p, err
+	}
+
+	return p, s.markVisited(p.URL)
+}
+
+// markVisited - Record the URL is finished with so it isn't visited again on resume
+func (s *crawlStore) markVisited(u string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.done[u] = true
+	_, err := fmt.Fprintln(s.visited, u)
+	if err != nil {
+		return fmt.Errorf("markVisited - %v", err)
+	}
+	return nil
+}
+
+// complete - Record the pages with whether their analysis was stored and mark the crawl as finished,
+// the next crawl starts again. The files are closed when the crawl stops but it isn't complete until
+// its pages are analysed
+func (s *crawlStore) complete(pages []Page) error {
+	name := filepath.Join(s.dir, storePages)
+	f, err := os.Create(name + ".tmp")
+	if err != nil {
+		return fmt.Errorf("complete - %v", err)
+	}
+	for _, p := range pages {
+		p.BodyData = nil
+		err = s.appendJSON(f, p)
+		if err != nil {
+			f.Close()
+			return err
+		}
+	}
+	err = f.Close()
+	if err != nil {
+		return fmt.Errorf("complete - %v", err)
+	}
+
+	// Replaced so an interrupted write doesn't lose the pages of the crawl
+	err = os.Rename(name+".tmp", name)
+	if err != nil {
+		return fmt.Errorf("complete - %v", err)
+	}
+
+	err = os.WriteFile(filepath.Join(s.dir, storeComplete), nil, 0644)
+	if err != nil {
+		return fmt.Errorf("complete - %v", err)
+	}
+	return nil
+}
+
+func (s *crawlStore) close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, f := range []*os.File{s.pages, s.frontier, s.visited} {
+		err := f.Close()
+		if err != nil {
+			return fmt.Errorf("close - %v", err)
+		}
+	}
+	return nil
+}
+
+// appendJSON - Write the value as a line of JSON
+func (s *crawlStore) appendJSON(f *os.File, v interface{}) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Errorf("appendJSON - %v", err)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	_, err = f.Write(append(data, '\n'))
+	if err != nil {
+		return fmt.Errorf("appendJSON - %v", err)
+	}
+	return nil
+}
+
+// readLines - Call fn with each non empty line of the file, a missing file has no lines
+func readLines(name string, fn func(line []byte)) error {
+	f, err := os.Open(name)
+	if os.IsNotExist(err) {
+		return nil
+	} else if err != nil {
+		return fmt.Errorf("readLines - %v", err)
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 64*1024), 16<<20)
+	for scanner.Scan() {
+		if len(scanner.Bytes()) > 0 {
+			fn(scanner.Bytes())
+		}
+	}
+
+	if err = scanner.Err(); err != nil {
+		return fmt.Errorf("readLines - %v:%v", name, err)
+	}
+	return nil
+}
+
+func appendFile(name string) (*os.File, error) {
+	f, err := os.OpenFile(name, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("appendFile - %v", err)
+	}
+	return f, nil
+}
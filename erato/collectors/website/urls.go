@@ -0,0 +1,139 @@
+package website
+
+import (
+	"fmt"
+	"net/url"
+	"path"
+	"regexp"
+	"strings"
+)
+
+// TrailingSlash settings - how the slash at the end of a URL path is normalised
+const (
+	// Add a slash to paths without a file extension e.g. /news becomes /news/
+	TrailingSlashAdd = "add"
+	// Remove the slash from every path except the root e.g. /news/ becomes /news
+	TrailingSlashRemove = "remove"
+)
+
+// Query parameters that only track the visitor and are always removed
+var DefaultStripQueryParams = []string{"utm_*", "fbclid", "gclid", "dclid", "msclkid", "mc_cid", "mc_eid", "_ga", "_gl", "yclid", "igshid"}
+
+// URLPattern - Compile an include or exclude pattern to match whole URLs
+// Patterns starting re: are regular expressions otherwise they are globs where * matches any characters
+// e.g. "https://example.com/news/*" or "re:/page/[0-9]+$"
+func URLPattern(pattern string) (*regexp.Regexp, error) {
+	if strings.HasPrefix(pattern, "re:") {
+		re, err := regexp.Compile(strings.TrimPrefix(pattern, "re:"))
+		if err != nil {
+			return nil, fmt.Errorf("URLPattern - %v", err)
+		}
+		return re, nil
+	}
+
+	return globPattern(pattern), nil
+}
+
+// globPattern - A glob as an anchored regular expression, only * is special
+// as ? and [ are common in URLs
+func globPattern(glob string) *regexp.Regexp {
+	parts := strings.Split(glob, "*")
+	for i := range parts {
+		parts[i] = regexp.QuoteMeta(parts[i])
+	}
+	return regexp.MustCompile("^" + strings.Join(parts, ".*") + "$")
+}
+
+// urlPatterns - Compile the patterns for colly's URL filters
+func urlPatterns(patterns []string) ([]*regexp.Regexp, error) {
+	var res []*regexp.Regexp
+	for _, p := range patterns {
+		re, err := URLPattern(p)
+		if err != nil {
+			return nil, fmt.Errorf("%v:%v", p, err)
+		}
+		res = append(res, re)
+	}
+	return res, nil
+}
+
+// urlRules - How URLs are normalised so each page is visited and catalogued once
+type urlRules struct {
+	// Names of the query parameters to remove, globs
+	stripParams   []*regexp.Regexp
+	trailingSlash string
+}
+
+func newURLRules(c *WebsiteConfig) urlRules {
+	var ur urlRules
+	for _, p := range append(append([]string{}, DefaultStripQueryParams...), c.StripQueryParams...) {
+		ur.stripParams = append(ur.stripParams, globPattern(p))
+	}
+	ur.trailingSlash = c.TrailingSlash
+	return ur
+}
+
+// normalise - The URL in its normal form, blank when it isn't a web page e.g. mailto: or javascript:
+// The fragment and stripped query parameters are removed, the other parameters are sorted,
+// the host is lower case without the default port and the trailing slash set
+func (ur urlRules) normalise(raw string) string {
+	u, err := url.Parse(strings.TrimSpace(raw))
+	if err != nil {
+		return ""
+	}
+
+	u.Scheme = strings.ToLower(u.Scheme)
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return ""
+	}
+
+	u.Fragment = ""
+	u.RawFragment = ""
+	u.Host = strings.ToLower(u.Host)
+	if (u.Scheme == "http" && u.Port() == "80") || (u.Scheme == "https" && u.Port() == "443") {
+		u.Host = strings.TrimSuffix(u.Host, ":"+u.Port())
+	}
+
+	if u.Path == "" {
+		u.Path = "/"
+	}
+	switch ur.trailingSlash {
+	case TrailingSlashAdd:
+		if !strings.HasSuffix(u.Path, "/") && path.Ext(u.Path) == "" {
+			u.Path += "/"
+			u.RawPath = ""
+		}
+	case TrailingSlashRemove:
+		if u.Path != "/" && strings.HasSuffix(u.Path, "/") {
+			u.Path = strings.TrimRight(u.Path, "/")
+			u.RawPath = ""
+			if u.Path == "" {
+				u.Path = "/"
+			}
+		}
+	}
+
+	if u.RawQuery != "" {
+		query := u.Query()
+		for name := range query {
+			if ur.stripped(name) {
+				query.Del(name)
+			}
+		}
+		// Encode sorts the parameters
+		u.RawQuery = query.Encode()
+	}
+	u.ForceQuery = false
+
+	return u.String()
+}
+
+// stripped - The query parameter is one to remove
+func (ur urlRules) stripped(name string) bool {
+	for _, re := range ur.stripParams {
+		if re.MatchString(name) {
+			return true
+		}
+	}
+	return false
+}
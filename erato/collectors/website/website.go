@@ -1,18 +1,35 @@
 package website
 
 import (
+	"Erato/erato/preparers/readability"
 	"Erato/erato/utils"
+	"bytes"
+	"encoding/json"
 	"fmt"
+	"io"
 	"log"
-	"math/rand"
+	"mime"
+	"net/http"
 	"net/url"
+	"os"
 	"path"
 	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
 
+	"github.com/PuerkitoBio/goquery"
 	"github.com/gocolly/colly"
 	"github.com/google/uuid"
 )
 
+// DefaultUserAgent - Identifies the crawler honestly when no UserAgent is configured
+const DefaultUserAgent = "Erato/1.0 (document curation crawler)"
+
+// DefaultMaxDocumentSize - The largest page or linked document downloaded when MaxDocumentSize isn't set
+const DefaultMaxDocumentSize = 50 << 20
+
 // Using the Colly package to scrape a website
 // download the content
 // Website - Interface for the Website
@@ -23,14 +40,72 @@ type WebsiteCollector struct {
 	Colly          *colly.Collector
 	AllowedDomains []string
 	AllSitePages   []Page
+	// Hashes of the blocks found on RepeatedBlockPages or more pages
+	RepeatedBlocks map[string]bool
 	Debug          bool
+	// lastMods - lastmod of the pages in the sitemaps
+	lastMods map[string]time.Time
+	// mu - colly calls back from several goroutines when crawling in parallel
+	mu sync.Mutex
+	// store - The crawl state on disk when StateDir is set
+	store *crawlStore
+	// urls - How links are normalised before they are visited
+	urls urlRules
+	// pageURLs - The URLs of the pages in AllSitePages so a page found at two URLs is added once
+	pageURLs map[string]bool
+	// links - The links found on each page by the URL requested
+	links map[string]map[string]bool
+	// canonicals - The URL pages were catalogued at by the URL requested when they differ
+	canonicals map[string]string
+	// auth - Signs in to the site when the Auth config is set
+	auth *authenticator
 }
 
 type WebsiteConfig struct {
 	URL            string
 	AllowedDomains []string
 	MaxDepth       int
-	Debug          bool
+	// Main content extraction - CSS selectors for the content to keep and the elements to remove
+	IncludeSelectors []string
+	ExcludeSelectors []string
+	// Blocks of text on at least this many pages are removed, 0 keeps them
+	RepeatedBlockPages int
+	// Prepare the whole page rather than the main content
+	KeepFullPage bool
+	// Crawl politeness - DefaultUserAgent is used when UserAgent isn't set
+	UserAgent       string
+	IgnoreRobotsTxt bool
+	// Parallelism and delays between requests apply to each allowed domain
+	Parallelism int
+	Delay       time.Duration
+	RandomDelay time.Duration
+	// Sitemaps to seed the crawl - when not set those in robots.txt or /sitemap.xml are used
+	Sitemaps     []string
+	SkipSitemaps bool
+	// URLs are only visited when they match an IncludeURLs pattern, if there are any, and no ExcludeURLs pattern
+	// Globs where * matches any characters or regular expressions starting re: (see URLPattern)
+	IncludeURLs []string
+	ExcludeURLs []string
+	// Query parameters to remove from links, globs e.g. "sort" or "filter_*"
+	// The DefaultStripQueryParams for tracking are always removed
+	StripQueryParams []string
+	// TrailingSlashAdd or TrailingSlashRemove, when not set the paths are left as they are
+	TrailingSlash string
+	// Catalog pages at their own URL rather than the <link rel="canonical"> URL
+	IgnoreCanonical bool
+	// Linked documents e.g. PDFs are downloaded even from pages at the MaxDepth
+	// unless SkipDocuments is set, those bigger than MaxDocumentSize bytes are left out
+	SkipDocuments   bool
+	MaxDocumentSize int
+	// File extensions of the linked documents to download e.g. ".pdf", those the preparers support
+	DocumentExtensions []string
+	// Auth - Headers, cookies and form login for intranets and portals
+	Auth AuthConfig
+	// Directory to keep the crawl state and page bodies in so an interrupted crawl
+	// resumes where it stopped and the next crawl only fetches the pages that changed
+	// When not set everything is held in memory and every page is fetched
+	StateDir string
+	Debug    bool
 }
 
 type Page struct {
@@ -41,8 +116,28 @@ type Page struct {
 	ParentURL string
 	Name      string
 	TypeName  string
-	Type      interface{}
-	BodyData  []byte
+	// From the Content-Type header
+	MIMEType string
+	Type     interface{}
+	// From the Content-Disposition header of a downloaded document
+	FileName string
+	// Links on the page to the allowed domains for the link graph
+	Links    []string
+	BodyData []byte
+	// The body is in BodyFile rather than BodyData when the crawl state is on disk
+	BodyFile string
+	Size     int64
+	// From the Last-Modified header or when the server doesn't provide one the sitemap's lastmod
+	TimeLastModified time.Time
+	// Validators for a conditional request on the next crawl
+	ETag         string
+	LastModified string
+	ContentHash  string
+	// Unchanged since the last crawl - the server said Not Modified or the body is the same
+	Unchanged bool
+	// The analysis of the page was stored, set when the crawl is committed
+	// Only a page that was stored last crawl can be unchanged
+	Stored bool
 }
 
 func NewCollector(cc interface{}) (*WebsiteCollector, error) {
@@ -53,60 +148,94 @@ func NewCollector(cc interface{}) (*WebsiteCollector, error) {
 		return nil, fmt.Errorf("NewWebsiteCollector - Error asserting config type")
 	}
 
-	// TODO - A config slice to create some optionality on Collector
+	if c.UserAgent == "" {
+		c.UserAgent = DefaultUserAgent
+	}
+	if c.Parallelism < 1 {
+		c.Parallelism = 1
+	}
+	if c.MaxDocumentSize <= 0 {
+		c.MaxDocumentSize = DefaultMaxDocumentSize
+	}
+
+	include, err := urlPatterns(c.IncludeURLs)
+	if err != nil {
+		return nil, fmt.Errorf("NewWebsiteCollector - IncludeURLs - %v", err)
+	}
+	exclude, err := urlPatterns(c.ExcludeURLs)
+	if err != nil {
+		return nil, fmt.Errorf("NewWebsiteCollector - ExcludeURLs - %v", err)
+	}
 
 	cly := colly.NewCollector(
 		colly.AllowedDomains(c.AllowedDomains...),
+		colly.URLFilters(include...),
+		colly.DisallowedURLFilters(exclude...),
 		colly.MaxDepth(c.MaxDepth),
-		colly.UserAgent(requestAgent()),
+		colly.UserAgent(c.UserAgent),
+		// One byte over so a body cut short can be told from one of exactly MaxDocumentSize
+		colly.MaxBodySize(c.MaxDocumentSize+1),
+		colly.Async(true),
 		// colly.Debugger(&debug.LogDebugger{}),
 		// TODO attach a debugger to the collector if debug mode is set
 	)
 
-	// Limit the number of threads started by colly to two
-	// when visiting links which domains' matches "*httpbin.*" glob
-	// cly.Limit(&colly.LimitRule{
-	// DomainGlob:  "*httpbin.*",
-	// Parallelism: 2,
-	// 	RandomDelay: 5 * time.Second,
-	// })
+	// colly ignores robots.txt by default
+	cly.IgnoreRobotsTxt = c.IgnoreRobotsTxt
+
+	// Responses that say they are bigger than the MaxDocumentSize aren't downloaded
+	cly.WithTransport(&maxSizeTransport{next: http.DefaultTransport, maxSize: c.MaxDocumentSize})
+
+	// A rule for each domain so the limits apply to each of them rather than all together
+	// Without any domains one rule covers them all, colly doesn't limit a domain without a rule
+	var domainGlobs []string
+	for _, domain := range c.AllowedDomains {
+		domain = strings.TrimSpace(domain)
+		if domain != "" {
+			domainGlobs = append(domainGlobs, domain)
+		}
+	}
+	if len(domainGlobs) == 0 {
+		domainGlobs = []string{"*"}
+	}
+
+	for _, domainGlob := range domainGlobs {
+		err = cly.Limit(&colly.LimitRule{
+			DomainGlob:  domainGlob,
+			Parallelism: c.Parallelism,
+			Delay:       c.Delay,
+			RandomDelay: c.RandomDelay,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("NewWebsiteCollector - LimitRule for Domain:%v - %v", domainGlob, err)
+		}
+	}
 
 	w := WebsiteCollector{
 		Config:         c,
 		SiteURL:        c.URL,
 		Colly:          cly,
 		AllowedDomains: c.AllowedDomains,
+		lastMods:       make(map[string]time.Time),
+		urls:           newURLRules(c),
+		pageURLs:       make(map[string]bool),
+		links:          make(map[string]map[string]bool),
+		canonicals:     make(map[string]string),
 	}
 
-	return &w, err
-}
-
-// requestAgent - Return a random user agent from the list
-func requestAgent() string {
-
-	x := []string{
-		`Mozilla/5.0 (Linux; Android 13; SM-S908B) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/112.0.0.0 Mobile Safari/537.36`,
-		`Mozilla/5.0 (iPhone14,3; U; CPU iPhone OS 15_0 like Mac OS X) AppleWebKit/602.1.50 (KHTML, like Gecko) Version/10.0 Mobile/19A346 Safari/602.1`,
-		`Mozilla/5.0 (Linux; Android 13; Pixel 6) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/112.0.0.0 Mobile Safari/537.36`,
-		`Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/123.0.0.0 Safari/537.36`,
-		`Mozilla/5.0 (Windows NT 10.0; Win64; x64; rv:124.0) Gecko/20100101 Firefox/124.0`,
-		`Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/123.0.0.0 Safari/537.36 Edg/123.0.2420.81`,
-		`Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/123.0.0.0 Safari/537.36 OPR/109.0.0.0`,
-		`Mozilla/5.0 (Macintosh; Intel Mac OS X 10_15_7) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/123.0.0.0 Safari/537.36`,
-		`Mozilla/5.0 (Macintosh; Intel Mac OS X 14.4; rv:124.0) Gecko/20100101 Firefox/124.0`,
-		`Mozilla/5.0 (Macintosh; Intel Mac OS X 14_4_1) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/17.4.1 Safari/605.1.15`,
-		`Mozilla/5.0 (Macintosh; Intel Mac OS X 14_4_1) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/123.0.0.0 Safari/537.36 OPR/109.0.0.0`,
-		`Mozilla/5.0 (X11; Linux x86_64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/123.0.0.0 Safari/537.36`,
-		`Mozilla/5.0 (X11; Linux i686; rv:124.0) Gecko/20100101 Firefox/124.0`,
-		`Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/58.0.3029.110 Safari/537.3"`,
+	if c.Auth.IsSet() {
+		w.auth, err = newAuthenticator(c)
+		if err != nil {
+			return nil, fmt.Errorf("NewWebsiteCollector - Auth - %v", err)
+		}
+		cly.SetCookieJar(w.auth.jar)
 	}
 
-	// create a raondom number between 0 and the length of the slice of x-1
-	return x[rand.Intn(len(x)-1)]
-
+	return &w, err
 }
 
 // CatalogContents - Catalog the contents of the website
+// The crawl starts from the SiteURL and the pages in the site's sitemaps
 func (w *WebsiteCollector) CatalogContents() error {
 	var err error
 
@@ -118,8 +247,91 @@ func (w *WebsiteCollector) CatalogContents() error {
 		fmt.Println("CatalogContents - SiteURL=", w.SiteURL)
 
 	}
-	// Start scraping on the site
-	err = w.Colly.Visit(w.SiteURL)
+
+	// Sign in before the crawl so the sitemaps and pages are fetched with the session
+	if w.auth != nil && w.Config.Auth.LoginURL != "" {
+		err = w.auth.login()
+		if err != nil {
+			return fmt.Errorf("CatalogContents - %v", err)
+		}
+	}
+
+	var seeds []SitemapURL
+	if !w.Config.SkipSitemaps {
+		seeds, err = w.sitemapURLs()
+		if err != nil {
+			return fmt.Errorf("CatalogContents - Sitemaps - %v", err)
+		}
+		if debug {
+			fmt.Printf("CatalogContents - DEBUG - %v pages in the sitemaps\n", len(seeds))
+		}
+	}
+
+	for i := range seeds {
+		seeds[i].Loc = w.urls.normalise(seeds[i].Loc)
+	}
+	for _, seed := range seeds {
+		if !seed.LastMod.IsZero() {
+			w.lastMods[seed.Loc] = seed.LastMod
+		}
+	}
+
+	var resume []frontierLink
+	if w.Config.StateDir != "" {
+		var pages []Page
+		w.store, pages, resume, err = openCrawlStore(w.Config.StateDir)
+		if err != nil {
+			return fmt.Errorf("CatalogContents - %v", err)
+		}
+		w.AllSitePages = append(w.AllSitePages, pages...)
+		for _, p := range pages {
+			w.pageURLs[p.URL] = true
+		}
+		if debug && len(pages) > 0 {
+			fmt.Printf("CatalogContents - DEBUG - Resuming crawl with %v pages and %v links to visit\n", len(pages), len(resume))
+		}
+	}
+
+	// Start scraping on the site, a resumed crawl skips it when it has been scraped
+	siteURL := w.urls.normalise(w.SiteURL)
+	if siteURL == "" {
+		return fmt.Errorf("CatalogContents - SiteURL is not a web page:%v", w.SiteURL)
+	}
+	err = w.Colly.Visit(siteURL)
+	if err != nil {
+		return err
+	}
+
+	for _, l := range resume {
+		verr := w.resumeVisit(l)
+		if verr != nil && debug {
+			fmt.Printf("CatalogContents - DEBUG - Resume link:%v - %v\n", l.URL, verr)
+		}
+	}
+
+	for _, seed := range seeds {
+		if seed.Loc == "" {
+			continue
+		}
+		// Pages outside the allowed domains, blocked by robots.txt or already visited are skipped
+		verr := w.Colly.Visit(seed.Loc)
+		if verr != nil && debug {
+			fmt.Printf("CatalogContents - DEBUG - Sitemap page:%v - %v\n", seed.Loc, verr)
+		}
+	}
+
+	w.Colly.Wait()
+
+	// The crawl is only complete once the catalog is stored, see CommitCatalog
+	if w.store != nil {
+		err = w.store.close()
+		if err != nil {
+			return fmt.Errorf("CatalogContents - %v", err)
+		}
+	}
+
+	// The boilerplate can only be found once all the pages are in
+	w.findRepeatedBlocks()
 
 	return err
 
@@ -132,9 +344,31 @@ func (w *WebsiteCollector) setupCollyBehaviour() {
 
 	// Log the site vist
 	w.Colly.OnRequest(func(r *colly.Request) {
+		// Finished with in an earlier run of the crawl
+		if w.store != nil && w.store.isVisited(r.URL.String()) {
+			r.Abort()
+			return
+		}
 		if debug {
 			log.Println("Visiting", r.URL)
 		}
+
+		if w.auth != nil {
+			session := w.auth.setHeaders(*r.Headers)
+			r.Ctx.Put("AuthSession-"+r.URL.String(), strconv.Itoa(session))
+		}
+
+		// Ask the server for the page only if it changed since the last crawl
+		if w.store != nil {
+			if prev, ok := w.store.previousPage(r.URL.String()); ok {
+				if prev.ETag != "" {
+					r.Headers.Set("If-None-Match", prev.ETag)
+				}
+				if prev.LastModified != "" {
+					r.Headers.Set("If-Modified-Since", prev.LastModified)
+				}
+			}
+		}
 		// Save the requested URL into context
 		// r.Ctx.Put("Regurl", r.URL.String())
 
@@ -142,32 +376,45 @@ func (w *WebsiteCollector) setupCollyBehaviour() {
 
 	// Setup OnError behaviour for a page visit
 	w.Colly.OnError(func(r *colly.Response, err error) {
+		if r.StatusCode == http.StatusNotModified && w.addUnchangedPage(r) {
+			return
+		}
+
+		// The session has expired so sign in again and retry the page once
+		if r.StatusCode == http.StatusUnauthorized && w.auth != nil {
+			key := "AuthRetry-" + r.Request.URL.String()
+			if r.Ctx.Get(key) == "" {
+				r.Ctx.Put(key, "true")
+				session, _ := strconv.Atoi(r.Ctx.Get("AuthSession-" + r.Request.URL.String()))
+				aerr := w.auth.refresh(session)
+				if aerr == nil {
+					aerr = r.Request.Retry()
+				}
+				if aerr == nil {
+					return
+				}
+				log.Println("CatalogContents - ERROR: Auth -", aerr)
+			}
+		}
+
 		// Need to register connection
 		log.Println("CatalogContents - ERROR:", r.StatusCode, err)
+		if w.store != nil {
+			serr := w.store.markVisited(r.Request.URL.String())
+			if serr != nil {
+				log.Println("CatalogContents - ERROR:", serr)
+			}
+		}
 	})
 
 	// The recursive link visiting function
 	w.Colly.OnHTML("a[href]", func(e *colly.HTMLElement) {
+		w.visitLink(e.Request, e.Attr("href"))
+	})
 
-		r := e.Response
-
-		// Save the previous page URL into context
-		parent := r.Request.URL.String()
-		link := e.Attr("href")
-		key := "ParentPage-" + e.Request.AbsoluteURL(link)
-		r.Ctx.Put(key, parent)
-
-		fmt.Printf("DEBUG - OnHTML - ParentPage:%v - Link:%v\n", parent, link)
-
-		// if debug {
-		// 	fmt.Printf("Link found:%s\n", link)
-		// }
-		// Visit link found on page Only those links are visited which are in AllowedDomains
-		// sleep for a random amount of time betweek 100 and 500 milliseconds
-
-		// time.Sleep(time.Duration(rand.Intn(500)+100) * time.Millisecond)
-		e.Request.Visit(e.Request.AbsoluteURL(link))
-
+	// The page's canonical URL which it is catalogued at
+	w.Colly.OnHTML("link[rel~=canonical][href]", func(e *colly.HTMLElement) {
+		e.Response.Ctx.Put("Canonical-"+e.Request.URL.String(), e.Request.AbsoluteURL(e.Attr("href")))
 	})
 
 	// OnScraped event adds to the collectors list of objects
@@ -185,14 +432,70 @@ func (w *WebsiteCollector) setupCollyBehaviour() {
 
 		fmt.Printf("DEBUG - OnScraped - PreviousPage=%v\n", prev)
 
+		// colly stops reading at MaxBodySize so the body is cut short
+		// when the server didn't send a Content-Length
+		if len(r.Body) > w.Config.MaxDocumentSize {
+			log.Printf("CatalogContents - WARNING - %v is bigger than MaxDocumentSize:%v\n", r.Request.URL, w.Config.MaxDocumentSize)
+			if w.store != nil {
+				err := w.store.markVisited(r.Request.URL.String())
+				if err != nil {
+					log.Println("CatalogContents - ERROR:", err)
+				}
+			}
+			return
+		}
+
 		// Create the Content of type Page
+		// The type is matched to a preparer from the extension, Content-Type and body when cataloged
 		page := Page{
-			URL:       r.Request.URL.String(),
-			ParentURL: prev,
-			UniqueID:  uuid.NewString(),
-			// TODO - less hacky and get actual content type
-			TypeName: ".html",
-			BodyData: r.Body,
+			URL:          w.canonicalURL(r),
+			ParentURL:    prev,
+			UniqueID:     uuid.NewString(),
+			TypeName:     strings.ToLower(path.Ext(r.Request.URL.Path)),
+			MIMEType:     r.Headers.Get("Content-Type"),
+			BodyData:     r.Body,
+			ETag:         r.Headers.Get("ETag"),
+			LastModified: r.Headers.Get("Last-Modified"),
+			ContentHash:  utils.HashBytes(r.Body),
+			Links:        w.pageLinks(r.Request.URL.String()),
+		}
+
+		if page.URL != r.Request.URL.String() {
+			w.mu.Lock()
+			w.canonicals[r.Request.URL.String()] = page.URL
+			w.mu.Unlock()
+		}
+
+		// A document served from a URL without its name e.g. /download?id=42
+		if _, params, err := mime.ParseMediaType(r.Headers.Get("Content-Disposition")); err == nil && params["filename"] != "" {
+			page.FileName = path.Base(params["filename"])
+			if page.TypeName == "" {
+				page.TypeName = strings.ToLower(path.Ext(page.FileName))
+			}
+		}
+
+		// Finished with the URL that was requested as well as the canonical one
+		if w.store != nil && page.URL != r.Request.URL.String() {
+			err := w.store.markVisited(r.Request.URL.String())
+			if err != nil {
+				log.Println("CatalogContents - ERROR:", err)
+			}
+		}
+
+		// The server doesn't support conditional requests but the page is the same
+		if w.store != nil {
+			if last, ok := w.store.previousPage(page.URL); ok && last.ContentHash == page.ContentHash {
+				page.UniqueID = last.UniqueID
+				page.BodyFile = last.BodyFile
+				page.Size = last.Size
+				page.Unchanged = true
+			}
+		}
+
+		if lm, err := http.ParseTime(r.Headers.Get("Last-Modified")); err == nil {
+			page.TimeLastModified = lm
+		} else if lm, ok := w.lastMods[page.URL]; ok {
+			page.TimeLastModified = lm
 		}
 
 		page.addPageToCollection(w)
@@ -200,14 +503,246 @@ func (w *WebsiteCollector) setupCollyBehaviour() {
 	})
 }
 
+// visitLink - Visit a link found on a page
+// Only those links are visited which are in AllowedDomains
+func (w *WebsiteCollector) visitLink(req *colly.Request, link string) {
+
+	// Save the previous page URL into context
+	parent := req.URL.String()
+	abs := w.urls.normalise(req.AbsoluteURL(link))
+	if abs == "" {
+		// mailto:, tel:, javascript: and links to a fragment of the page
+		return
+	}
+	req.Ctx.Put("ParentPage-"+abs, parent)
+
+	if u, err := url.Parse(abs); err == nil && w.allowedHost(u.Host, req.URL.Host) {
+		w.recordLink(parent, abs)
+	}
+
+	fmt.Printf("DEBUG - OnHTML - ParentPage:%v - Link:%v\n", parent, link)
+
+	var err error
+	depth := req.Depth + 1
+	if w.isDocumentLink(abs) {
+		if w.Config.SkipDocuments {
+			return
+		}
+		// Documents have no links to follow so are downloaded whatever the depth of the page
+		depth = 1
+		err = w.Colly.Request("GET", abs, nil, req.Ctx, nil)
+	} else {
+		err = req.Visit(abs)
+	}
+
+	if err == nil && w.store != nil {
+		// Remember the link so it's visited if the crawl is interrupted before it's scraped
+		err = w.store.queue(frontierLink{URL: abs, ParentURL: parent, Depth: depth})
+		if err != nil {
+			log.Println("CatalogContents - ERROR:", err)
+		}
+	}
+}
+
+// isDocumentLink - The link is to a document with one of the DocumentExtensions e.g. a .pdf
+func (w *WebsiteCollector) isDocumentLink(link string) bool {
+	u, err := url.Parse(link)
+	if err != nil {
+		return false
+	}
+
+	ext := strings.ToLower(path.Ext(u.Path))
+	if ext == "" {
+		return false
+	}
+
+	for _, de := range w.Config.DocumentExtensions {
+		if strings.ToLower(de) == ext {
+			return true
+		}
+	}
+	return false
+}
+
+// maxSizeTransport - Fails a response with a Content-Length bigger than the maxSize
+// before its body is read
+type maxSizeTransport struct {
+	next    http.RoundTripper
+	maxSize int
+}
+
+func (t *maxSizeTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	resp, err := t.next.RoundTrip(req)
+	if err != nil {
+		return resp, err
+	}
+
+	if resp.ContentLength > int64(t.maxSize) {
+		resp.Body.Close()
+		return nil, fmt.Errorf("%v is %v bytes, bigger than MaxDocumentSize:%v", req.URL, resp.ContentLength, t.maxSize)
+	}
+	return resp, nil
+}
+
+// canonicalURL - The URL to catalog the page at
+// The <link rel="canonical"> URL when the page has one in the allowed domains
+func (w *WebsiteCollector) canonicalURL(r *colly.Response) string {
+	requested := r.Request.URL.String()
+	if w.Config.IgnoreCanonical {
+		return requested
+	}
+
+	canonical := w.urls.normalise(r.Ctx.Get("Canonical-" + requested))
+	if canonical == "" {
+		return requested
+	}
+
+	cu, err := url.Parse(canonical)
+	if err != nil || !w.allowedHost(cu.Host, r.Request.URL.Host) {
+		return requested
+	}
+
+	return canonical
+}
+
+// allowedHost - The host is one of the AllowedDomains or the site's host when they aren't set
+func (w *WebsiteCollector) allowedHost(host string, siteHost string) bool {
+	if len(w.AllowedDomains) == 0 {
+		return host == siteHost
+	}
+	for _, domain := range w.AllowedDomains {
+		if strings.TrimSpace(domain) == host {
+			return true
+		}
+	}
+	return false
+}
+
+// addUnchangedPage - Add the page from the last crawl when the server says it's Not Modified
+// The links on the page are followed from the body kept by the last crawl
+func (w *WebsiteCollector) addUnchangedPage(r *colly.Response) bool {
+	if w.store == nil {
+		return false
+	}
+
+	page, ok := w.store.previousPage(r.Request.URL.String())
+	if !ok {
+		return false
+	}
+
+	body, err := page.body()
+	if err != nil {
+		log.Println("addUnchangedPage - ERROR:", err)
+		return false
+	}
+
+	page.ParentURL = r.Ctx.Get("ParentPage-" + page.URL)
+	page.Unchanged = true
+	// The server may send new validators with the 304
+	if etag := r.Headers.Get("ETag"); etag != "" {
+		page.ETag = etag
+	}
+	if lm := r.Headers.Get("Last-Modified"); lm != "" {
+		page.LastModified = lm
+	}
+
+	if w.Config.Debug {
+		log.Println("Not Modified", page.URL)
+	}
+
+	if page.isHTML() {
+		doc, err := goquery.NewDocumentFromReader(bytes.NewReader(body))
+		if err == nil {
+			doc.Find("a[href]").Each(func(i int, s *goquery.Selection) {
+				w.visitLink(r.Request, s.AttrOr("href", ""))
+			})
+		}
+		page.Links = w.pageLinks(r.Request.URL.String())
+	}
+
+	page.addPageToCollection(w)
+
+	return true
+}
+
+// resumeVisit - Visit a link queued by an interrupted crawl at the depth it was found
+func (w *WebsiteCollector) resumeVisit(l frontierLink) error {
+	// A request for the parent page so the link is visited at its depth and the
+	// parent is in the context as it is for links found on a page
+	data, err := json.Marshal(map[string]string{"URL": l.ParentURL, "Method": "GET"})
+	if err != nil {
+		return err
+	}
+
+	req, err := w.Colly.UnmarshalRequest(data)
+	if err != nil {
+		return err
+	}
+	req.Depth = l.Depth - 1
+	req.Ctx.Put("ParentPage-"+l.URL, l.ParentURL)
+
+	return req.Visit(l.URL)
+}
+
 // addPageToCollection - Add the page to the collection
+// With a StateDir the body is written to disk and the page is recorded for a resumed crawl
 func (p Page) addPageToCollection(w *WebsiteCollector) {
 
+	// The same page at another URL e.g. a page and its canonical URL
+	w.mu.Lock()
+	if w.pageURLs[p.URL] {
+		w.mu.Unlock()
+		if w.Config.Debug {
+			fmt.Printf("addPageToCollection - DEBUG - Already collected Page:%v\n", p.URL)
+		}
+		return
+	}
+	w.pageURLs[p.URL] = true
+	w.mu.Unlock()
+
+	if w.store != nil {
+		var err error
+		p, err = w.store.addPage(p)
+		if err != nil {
+			log.Println("addPageToCollection - ERROR:", err)
+		}
+	}
+
 	// add to the list of successfuly collected pages
+	w.mu.Lock()
 	w.AllSitePages = append(w.AllSitePages, p)
+	w.mu.Unlock()
 
 }
 
+// CommitCatalog - Mark the crawl as complete once the catalog has been analysed and stored
+// so a crawl interrupted before then resumes and its pages are analysed again
+// The pages whose analysis wasn't stored are fetched and analysed again by the next crawl
+func (w *WebsiteCollector) CommitCatalog(unstored []interface{}) error {
+	if w.store == nil {
+		return nil
+	}
+
+	failed := make(map[string]bool)
+	for _, ref := range unstored {
+		if p, ok := ref.(*Page); ok {
+			failed[p.UniqueID] = true
+		}
+	}
+
+	pages := make([]Page, len(w.AllSitePages))
+	for i, p := range w.AllSitePages {
+		p.Stored = !failed[p.UniqueID]
+		pages[i] = p
+	}
+
+	err := w.store.complete(pages)
+	if err != nil {
+		return fmt.Errorf("CommitCatalog - %v", err)
+	}
+	return nil
+}
+
 func (w *WebsiteCollector) DumpCatalogFileNames() {
 	for _, p := range w.AllSitePages {
 		fmt.Printf("DumpCatalogFileNames - Page=%v\n", p.URL)
@@ -232,18 +767,91 @@ func (w *WebsiteCollector) AllContentRefs() []interface{} {
 
 // Return the Content type
 func (p *Page) ContentType() string {
-	// TODO - HACKY HACK
-	return ".html"
+	return p.MIMEType
 }
 
 // DownloadContentData - Download the content data
+// HTML pages are reduced to their main content unless KeepFullPage is set
 func (w *WebsiteCollector) DownloadContentData(pr interface{}) (*[]byte, error) {
 	p := pr.(*Page)
 	// TODO - add check assertion
 
 	// Return the body data which has already been captured by colly
-	data := &p.BodyData
-	return data, nil
+	body, err := p.body()
+	if err != nil {
+		return nil, fmt.Errorf("DownloadContentData - %v", err)
+	}
+	data := &body
+	if w.Config.KeepFullPage || !p.isHTML() {
+		return data, nil
+	}
+
+	e := readability.Extractor{
+		Include:  w.Config.IncludeSelectors,
+		Exclude:  w.Config.ExcludeSelectors,
+		Repeated: w.RepeatedBlocks,
+	}
+
+	main, err := e.MainContent(body)
+	if err != nil {
+		// Better to prepare the whole page than nothing
+		if w.Config.Debug {
+			fmt.Printf("DownloadContentData - DEBUG - No main content for Page:%v - %v\n", p.URL, err)
+		}
+		return data, nil
+	}
+
+	return &main, nil
+}
+
+// findRepeatedBlocks - Count the pages each block of text is on to find the navigation,
+// banners and footers that are repeated across the site
+func (w *WebsiteCollector) findRepeatedBlocks() {
+	minPages := w.Config.RepeatedBlockPages
+	if minPages <= 0 || w.Config.KeepFullPage {
+		return
+	}
+
+	counts := make(map[string]int)
+	for i := range w.AllSitePages {
+		p := &w.AllSitePages[i]
+		if !p.isHTML() {
+			continue
+		}
+
+		body, err := p.body()
+		if err != nil {
+			continue
+		}
+
+		hashes, err := readability.BlockHashes(body)
+		if err != nil {
+			continue
+		}
+		for _, h := range hashes {
+			counts[h]++
+		}
+	}
+
+	w.RepeatedBlocks = make(map[string]bool)
+	for h, count := range counts {
+		if count >= minPages {
+			w.RepeatedBlocks[h] = true
+		}
+	}
+
+	if w.Config.Debug {
+		fmt.Printf("findRepeatedBlocks - DEBUG - %v blocks repeated on %v or more pages\n", len(w.RepeatedBlocks), minPages)
+	}
+}
+
+// isHTML - The page is HTML from its Content-Type or when there isn't one its body
+func (p *Page) isHTML() bool {
+	mimeType := p.MIMEType
+	if mimeType == "" {
+		mimeType = http.DetectContentType(p.PeekContent(512))
+	}
+	return strings.Contains(strings.ToLower(mimeType), "html")
 }
 
 // ContentRef - Interface for the Data that is sourced
@@ -253,6 +861,9 @@ func (p *Page) GetUniqueID() string {
 
 }
 func (p *Page) GetName() string {
+	if p.FileName != "" {
+		return p.FileName
+	}
 	// TODO - this maynot be index.html
 	u, _ := url.Parse(p.URL)
 	return path.Base(u.Path)
@@ -264,6 +875,9 @@ func (p *Page) GetFileName() string {
 	// log.Fatal("GetFileName - Not implemented")
 	log.Printf("GetFileName - FIX This so there is no / for /index.htm - Not implemented")
 	// FIX This so there is no / for /index.htm
+	if p.FileName != "" {
+		return p.FileName
+	}
 
 	u, _ := url.Parse(p.URL)
 	return path.Base(u.Path)
@@ -291,3 +905,52 @@ func (p *Page) GetPathHash() string {
 func (p *Page) GetParentLocation() string {
 	return p.ParentURL
 }
+
+func (p *Page) GetTimeLastModified() time.Time {
+	return p.TimeLastModified
+}
+
+func (p *Page) GetSize() int64 {
+	if p.BodyFile != "" {
+		return p.Size
+	}
+	return int64(len(p.BodyData))
+}
+
+// IsUnchanged - The page hasn't changed since the last crawl so needn't be analysed again
+func (p *Page) IsUnchanged() bool {
+	return p.Unchanged
+}
+
+// GetMIMEType - The Content-Type the server sent with the page
+func (p *Page) GetMIMEType() string {
+	return p.MIMEType
+}
+
+// PeekContent - The start of the body which colly has already downloaded
+func (p *Page) PeekContent(n int) []byte {
+	if p.BodyFile != "" {
+		f, err := os.Open(p.BodyFile)
+		if err != nil {
+			return nil
+		}
+		defer f.Close()
+
+		data := make([]byte, n)
+		n, _ = io.ReadFull(f, data)
+		return data[:n]
+	}
+
+	if n > len(p.BodyData) {
+		n = len(p.BodyData)
+	}
+	return p.BodyData[:n]
+}
+
+// body - The body of the page from memory or disk
+func (p *Page) body() ([]byte, error) {
+	if p.BodyFile != "" {
+		return os.ReadFile(p.BodyFile)
+	}
+	return p.BodyData, nil
+}
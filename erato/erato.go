@@ -2,14 +2,20 @@ package erato
 
 import (
 	"Erato/erato/analysers/openai"
+	filesystem "Erato/erato/collectors/filesystem"
 	sharepoint "Erato/erato/collectors/sharepoint"
 	website "Erato/erato/collectors/website"
 	"Erato/erato/preparers/content"
+	"errors"
+	"os"
+	"strings"
 	"time"
 
 	"Erato/erato/models"
 	"fmt"
 	"log"
+
+	"github.com/joho/godotenv"
 )
 
 const (
@@ -26,6 +32,11 @@ type Erato struct {
 	EratoPreparer      content.Config
 	EratoAnalysers     EratoAnalysers
 	EratoCollections   Collection
+	// Setup from the YML config and refered to by Name in the Collections
+	EratoConf  *EratoConf
+	Collectors map[string]models.Collector
+	Preparers  map[string]content.Config
+	Analysers  map[string]models.ContentAnalyser
 }
 
 // Analysers - Limited to 1-2-1 relationships
@@ -56,42 +67,342 @@ type Document struct {
 	PathHash       string
 	Type           string
 	FileExt        string
-	ContentType    interface{}
-	ContentRef     interface{}
-	ContentSource  string
-	OAIPrompt      string
+	MIMEType       string
+	// Source file details so stores can key on them
+	TimeLastModified time.Time
+	Size             int64
+	// Metadata kept by the content source e.g. SharePoint columns
+	SourceMetaData map[string]string `json:",omitempty"`
+	// Users and groups that can read the document at the source
+	Permissions []models.ContentPermission `json:",omitempty"`
+	// Properties read from the document content
+	Properties    models.ContentProperties
+	ContentType   interface{}
+	ContentRef    interface{}
+	ContentSource string
+	OAIPrompt     string
 	// ParagraphText   []string
 	// Documents have to be text in some form
 	NumTextChunks int
 	TextChunks    []string
+	// Where each text chunk came from - indexed by ParagraphNum-1 when the preparer supports it
+	TextChunkMetaData []models.ChunkMetaData
 	// DocMetaData     []ParagraphMetaData
 	DocMetaData     []interface{}
 	TypeDocMetaData map[string][]ParagraphMetaData
 	DocumentData    *[]byte
 	Curated         bool
-	AnalysisStats   DocumentAnalysisStats
-	AnalysisErrors  []error
+	// Unchanged since the last run so it isn't analysed again
+	Skipped bool
+	// Deleted from the source since the last run so its stored analysis is tombstoned
+	Deleted        bool
+	TimeDeleted    time.Time
+	AnalysisStats  DocumentAnalysisStats
+	AnalysisErrors []error
+	// The analysis was stored, or tombstoned, without errors by this run
+	stored bool
 }
 
-// NewErato - Setup everything from the config files
+// NewErato2 - Setup everything from the YML config file
+// Collectors, Preparers and Analysers are created once each and
+// the Collections are wired to them by Name
 func NewErato2(conf *EratoConf) (*Erato, error) {
 	var err error
-	var e Erato
+
+	if conf == nil {
+		return nil, errors.New("NewErato2 - conf is nil")
+	}
+
+	c := conf.GlobalConf()
+
+	e := Erato{
+		Conf:       c,
+		EratoConf:  conf,
+		Collectors: make(map[string]models.Collector),
+		Preparers:  make(map[string]content.Config),
+		Analysers:  make(map[string]models.ContentAnalyser),
+	}
+
+	err = e.setupCollectors()
+	if err != nil {
+		return nil, err
+	}
+
+	err = e.setupPreparers()
+	if err != nil {
+		return nil, err
+	}
+
+	err = e.setupAnalysers()
+	if err != nil {
+		return nil, err
+	}
+
+	// Wire the collections together from the named parts
+	for _, cc := range conf.Collections {
+		collector, ok := e.Collectors[cc.Collector]
+		if !ok {
+			return nil, fmt.Errorf("NewErato2 - Collection:%v - unknown Collector:%v", cc.Name, cc.Collector)
+		}
+
+		preparer, ok := e.Preparers[cc.Preparer]
+		if !ok {
+			return nil, fmt.Errorf("NewErato2 - Collection:%v - unknown Preparer:%v", cc.Name, cc.Preparer)
+		}
+
+		analyser, ok := e.Analysers[cc.Analyser]
+		if !ok {
+			return nil, fmt.Errorf("NewErato2 - Collection:%v - unknown Analyser:%v", cc.Name, cc.Analyser)
+		}
+
+		// Tokens are counted with the tokenizer of the model the chunks are sent to
+		for _, oaiConf := range conf.Analysers.OpenAI {
+			if oaiConf.Name == cc.Analyser {
+				preparer.Model = oaiConf.Model
+			}
+		}
+		err = preparer.CheckChunker()
+		if err != nil {
+			return nil, fmt.Errorf("NewErato2 - Collection:%v - Preparer:%v - %v", cc.Name, cc.Preparer, err)
+		}
+
+		coll := Collection{
+			Name: cc.Name,
+			ContentSource: ContentSource{
+				Name:      cc.Collector,
+				Collector: collector,
+			},
+			ContentPreparer: preparer,
+			ContentAnalyser: analyser,
+			Conf:            c,
+		}
+
+		e.ContentCollections = append(e.ContentCollections, coll)
+	}
 
 	return &e, err
 }
 
+// setupCollectors - Create each of the collectors declared in the config
+func (e *Erato) setupCollectors() error {
+	conf := e.EratoConf
+	c := e.Conf
+
+	for _, spConf := range conf.Collectors.Sharepoints {
+		modifiedAfter, err := sharepoint.ParseDate(spConf.ModifiedAfter)
+		if err != nil {
+			return fmt.Errorf("setupCollectors - Sharepoint:%v - %v", spConf.Name, err)
+		}
+
+		spc := sharepoint.SharePointConfig{
+			SPsiteName:     spConf.Name,
+			SPsiteURL:      spConf.SiteUrl,
+			SPdepthLimit:   depthLimit(spConf.DepthLimit, conf.Conf.DepthLimit),
+			SPsubSiteDepth: spConf.SubSiteDepth,
+			SPAuthFile:     spConf.SecretsFile,
+			SPauthStrategy: spConf.AuthStrategy,
+			SPexcludedPath: c.ExcludedPath,
+			Debug:          spConf.Debug || c.Debug,
+
+			SPincludedLibraries: spConf.Libraries,
+			SPexcludedLibraries: spConf.ExcludeLibraries,
+			SPmodifiedAfter:     modifiedAfter,
+			SPminFileSize:       spConf.MinFileSize << 10,
+			SPmaxFileSize:       spConf.MaxFileSize << 10,
+			SPcontentTypes:      spConf.ContentTypes,
+			SPcolumns:           spConf.Columns,
+			SPpermissions:       spConf.Permissions,
+			SPstateDir:          spConf.StateDir,
+		}
+
+		collector, err := sharepoint.NewCollector(&spc)
+		if err != nil {
+			return fmt.Errorf("setupCollectors - Sharepoint:%v - %v", spConf.Name, err)
+		}
+
+		err = e.addCollector(spConf.Name, collector)
+		if err != nil {
+			return err
+		}
+	}
+
+	for _, webConf := range conf.Collectors.Websites {
+		wc := website.WebsiteConfig{
+			URL:            webConf.SiteUrl,
+			AllowedDomains: strings.Split(webConf.AllowedDomains, ","),
+			MaxDepth:       depthLimit(webConf.MaxDepth, conf.Conf.DepthLimit),
+
+			IncludeSelectors:   webConf.IncludeSelectors,
+			ExcludeSelectors:   webConf.ExcludeSelectors,
+			RepeatedBlockPages: webConf.RepeatedBlockPages,
+			KeepFullPage:       webConf.KeepFullPage,
+
+			UserAgent:        webConf.UserAgent,
+			IgnoreRobotsTxt:  webConf.IgnoreRobotsTxt,
+			Parallelism:      webConf.Parallelism,
+			Delay:            time.Duration(webConf.Delay) * time.Millisecond,
+			RandomDelay:      time.Duration(webConf.RandomDelay) * time.Millisecond,
+			Sitemaps:         webConf.Sitemaps,
+			SkipSitemaps:     webConf.SkipSitemaps,
+			IncludeURLs:      webConf.IncludeURLs,
+			ExcludeURLs:      webConf.ExcludeURLs,
+			StripQueryParams: webConf.StripQueryParams,
+			TrailingSlash:    webConf.TrailingSlash,
+			IgnoreCanonical:  webConf.IgnoreCanonical,
+			SkipDocuments:    webConf.SkipDocuments,
+			MaxDocumentSize:  webConf.MaxDocumentSize << 20,
+			StateDir:         webConf.StateDir,
+			Debug:            webConf.Debug || c.Debug,
+
+			// The linked documents a preparer supports
+			DocumentExtensions: content.DocumentExtensions(),
+
+			Auth: website.AuthConfig{
+				SecretsFile:   webConf.Auth.SecretsFile,
+				Headers:       webConf.Auth.Headers,
+				CookieFile:    webConf.Auth.CookieFile,
+				LoginURL:      webConf.Auth.LoginURL,
+				UsernameField: webConf.Auth.UsernameField,
+				PasswordField: webConf.Auth.PasswordField,
+				LoginFields:   webConf.Auth.LoginFields,
+			},
+		}
+
+		collector, err := website.NewCollector(&wc)
+		if err != nil {
+			return fmt.Errorf("setupCollectors - Website:%v - %v", webConf.Name, err)
+		}
+
+		err = e.addCollector(webConf.Name, collector)
+		if err != nil {
+			return err
+		}
+	}
+
+	for _, fsConf := range conf.Collectors.Filesystems {
+		fc := filesystem.FilesystemConfig{
+			Name:           fsConf.Name,
+			RootDir:        fsConf.RootDir,
+			DepthLimit:     depthLimit(fsConf.DepthLimit, conf.Conf.DepthLimit),
+			ExcludedPath:   c.ExcludedPath,
+			FollowSymlinks: fsConf.FollowSymlinks,
+			ExpandArchives: fsConf.ExpandArchives,
+			Debug:          fsConf.Debug || c.Debug,
+		}
+
+		collector, err := filesystem.NewCollector(&fc)
+		if err != nil {
+			return fmt.Errorf("setupCollectors - Filesystem:%v - %v", fsConf.Name, err)
+		}
+
+		err = e.addCollector(fsConf.Name, collector)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// addCollector - Collector Names have to be unique across all the collector types
+func (e *Erato) addCollector(name string, collector models.Collector) error {
+	if _, ok := e.Collectors[name]; ok {
+		return fmt.Errorf("setupCollectors - duplicate Collector Name:%v", name)
+	}
+	e.Collectors[name] = collector
+	return nil
+}
+
+// setupPreparers - Create each of the content preparer configs
+func (e *Erato) setupPreparers() error {
+	for _, pc := range e.EratoConf.Preparers {
+		if _, ok := e.Preparers[pc.Name]; ok {
+			return fmt.Errorf("setupPreparers - duplicate Preparer Name:%v", pc.Name)
+		}
+
+		e.Preparers[pc.Name] = content.Config{
+			ParagraphMaxWordCount:  pc.MaxParagraphWordCount,
+			ParagraphMinWordCount:  pc.MinParagraphWordCount,
+			Chunker:                pc.Chunker,
+			ChunkMaxTokenCount:     pc.MaxChunkTokenCount,
+			ChunkOverlapTokenCount: pc.ChunkOverlapTokenCount,
+			Debug:                  pc.Debug || e.Conf.Debug,
+		}
+	}
+
+	return nil
+}
+
+// setupAnalysers - Create each of the analysers declared in the config
+func (e *Erato) setupAnalysers() error {
+	if len(e.EratoConf.Analysers.ComprehendMedical) > 0 {
+		return fmt.Errorf("setupAnalysers - ComprehendMedical analysers are not supported yet")
+	}
+
+	for _, oaiConf := range e.EratoConf.Analysers.OpenAI {
+		if _, ok := e.Analysers[oaiConf.Name]; ok {
+			return fmt.Errorf("setupAnalysers - duplicate Analyser Name:%v", oaiConf.Name)
+		}
+
+		// The API key is kept out of the config in a .env style secrets file
+		secrets, err := godotenv.Read(oaiConf.SecretsFile)
+		if err != nil {
+			return fmt.Errorf("setupAnalysers - OpenAI:%v - unable to read SecretsFile:%v - %v", oaiConf.Name, oaiConf.SecretsFile, err)
+		}
+
+		prompt, err := os.ReadFile(oaiConf.PromptFile)
+		if err != nil {
+			return fmt.Errorf("setupAnalysers - OpenAI:%v - unable to read PromptFile:%v - %v", oaiConf.Name, oaiConf.PromptFile, err)
+		}
+
+		oaic := openai.Config{
+			OAIdisable:          oaiConf.Disable,
+			OAIapibase:          oaiConf.BaseURL,
+			OAIapiKey:           secrets["OPENAI_KEY"],
+			OAImodel:            oaiConf.Model,
+			OAImaxTokens:        oaiConf.MaxTokens,
+			OAItemperature:      oaiConf.Temp,
+			OAIexampleFile:      oaiConf.PromptFile,
+			OIAprompt:           string(prompt),
+			OAIparralelRequests: oaiConf.Workers,
+			OpenAIworkerDelay:   oaiConf.WorkerDelay,
+			Debug:               oaiConf.Debug || e.Conf.Debug,
+		}
+
+		oai, err := openai.NewOpenAI(&oaic)
+		if err != nil {
+			return fmt.Errorf("setupAnalysers - OpenAI:%v - %v", oaiConf.Name, err)
+		}
+
+		e.Analysers[oaiConf.Name] = oai
+	}
+
+	return nil
+}
+
+// depthLimit - Use the collector setting and fallback to the global setting
+func depthLimit(limit int, global int) int {
+	if limit != 0 {
+		return limit
+	}
+	return global
+}
+
 // NewErato - Setup everything from the config files
 func NewErato(env string) (*Erato, error) {
 	var err error
 	var spc *sharepoint.SharePointColector
 	var web *website.WebsiteCollector
+	var fsc *filesystem.FilesystemCollector
 	// There is a seperation between the Config and
 	// 		the Erato Object
 	// 		the Collectors etc.
 
 	// Get the Config
-	c := NewConfig()
+	c, err := NewConfig()
+	if err != nil {
+		return nil, err
+	}
 
 	// Setup the collectors
 	// Todo - move to Collector setup function
@@ -107,6 +418,15 @@ func NewErato(env string) (*Erato, error) {
 		log.Fatal(err)
 	}
 
+	// The filesystem collector is optional - only setup when a root directory is set
+	if c.Filesystem.RootDir != "" {
+		fc := c.Filesystem
+		fsc, err = filesystem.NewCollector(&fc)
+		if err != nil {
+			log.Fatal(err)
+		}
+	}
+
 	oai, _ := openai.NewOpenAI(&c.XX_OAI)
 
 	e := Erato{
@@ -114,6 +434,7 @@ func NewErato(env string) (*Erato, error) {
 		EratoCollectors: EratoCollectors{
 			Sharepoint: spc,
 			Website:    web,
+			Filesystem: fsc,
 		},
 		EratoAnalysers: EratoAnalysers{
 			OpenAI: oai,
@@ -134,13 +455,19 @@ func printAnalysisStats(eratoStats ContentCatalogAnalysisStats, catalogName stri
 		"\tAnalysed=%v\n"+
 		"\tSuccesses=%v\n"+
 		"\tErrors=%v\n"+
-		"\tWarnings=%v\n",
+		"\tWarnings=%v\n"+
+		"\tUnsupported=%v\n"+
+		"\tSkipped=%v\n"+
+		"\tDeleted=%v\n",
 		catalogName,
 		eratoStats.Found,
 		eratoStats.Analysed,
 		eratoStats.Successes,
 		eratoStats.Errors,
-		eratoStats.Warnings)
+		eratoStats.Warnings,
+		eratoStats.Unsupported,
+		eratoStats.Skipped,
+		eratoStats.Deleted)
 
 	// } else {
 	// fmt.Printf("Erato - Success - Content Catalog=%v - Analysed=%v\n", catalogName, eratoStats.Analysed)
@@ -2,7 +2,9 @@ package erato
 
 import (
 	"Erato/erato/models"
+	content "Erato/erato/preparers/content"
 	"Erato/erato/utils"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"log"
@@ -15,7 +17,9 @@ import (
 
 // MetatData for a ParaGraph
 type ParagraphMetaData struct {
-	ParagraphNum      int
+	ParagraphNum int
+	// Where the paragraph came from e.g. the page, slide or sheet and cell range
+	models.ChunkMetaData
 	ParagraphType     string   `json:"Paragraph Type"`
 	ParagraphText     string   `json:"-"`
 	ParagraphSummary  string   `json:"Paragraph Summary"`
@@ -27,6 +31,8 @@ type ParagraphMetaData struct {
 	PeopleNames       []string `json:"People Names"`
 	OrganisationNames []string `json:"Organisation Names"`
 	BusinessUnits     []string `json:"Business"`
+	// The metadata from the content source e.g. SharePoint columns to compare with the extracted entities
+	SourceMetaData map[string]string `json:"Source MetaData,omitempty"`
 	// To be implemented
 	// QuantativeInformation []string `json:"Quantative Information"`
 	// ResultOutcome         string   `json:"Result or Outcome"`
@@ -55,6 +61,12 @@ type ContentCatalogAnalysisStats struct {
 	Successes int
 	Errors    int
 	Warnings  int
+	// Content left out of the catalog as no preparer matched its type
+	Unsupported int
+	// Content in the catalog that is unchanged since the last run
+	Skipped int
+	// Content deleted from the source since the last run
+	Deleted int
 }
 
 // AnalyseContentCatalog - Iterate through the Content Catalogue and Lanuch the Document Analysis
@@ -80,6 +92,11 @@ func (collection *Collection) AnalyseContentCatalog() error {
 		// Get the pointer from the content catalog list
 		doc := &ContCat[i]
 
+		// Analysed on an earlier run or deleted since
+		if doc.Skipped || doc.Deleted {
+			continue
+		}
+
 		// Check to see if the number of workers has been reached
 		if wrkNum == analysisWorkers {
 			// Wait for the workers to finish
@@ -123,6 +140,10 @@ func (collection *Collection) AnalyseContentCatalog() error {
 	for i := range ContCat {
 		doc := &ContCat[i]
 
+		if doc.Skipped || doc.Deleted {
+			continue
+		}
+
 		// Check Processing has happened
 		if doc.AnalysisStats.Processed > 0 {
 			eratoStats.Analysed++
@@ -155,6 +176,7 @@ func (collection *Collection) AnalyseContentCatalog() error {
 	}
 
 	// Print the final stats
+	collection.ContentCatalogsStats = eratoStats
 	printAnalysisStats(eratoStats, catalogName)
 
 	return err
@@ -214,20 +236,53 @@ func (doc *Document) AnalyseDocument(i int, wg *sync.WaitGroup, collection *Coll
 		fmt.Printf("\nLaunchAnalyseDocument - Downloaded:%v - size:%v\n", filepath.Base(doc.FileName), len((*doc.DocumentData)))
 	}
 
+	// The content may not be what the extension or MIME type said
+	// e.g. a PDF served from an .aspx URL so switch to the preparer for its signature
+	if doc.DocumentData != nil {
+		if reg, serr := content.Sniff(*doc.DocumentData); serr == nil && reg.FileExt != doc.FileExt {
+			if debug {
+				fmt.Printf("\nLaunchAnalyseDocument - DEBUG - FileName:%v is %v content not %v\n", doc.FileName, reg.FileExt, doc.FileExt)
+			}
+			doc.FileExt = reg.FileExt
+			doc.ContentType = reg.New(collection.ContentPreparer)
+		}
+	}
+
 	// Prepare the Content for the Analyser driven by
 	// the content type using the models.ContentPreparer
 	// Test that the interface{} implements the ContentPreparer
-	if contentTyper, ok := doc.ContentType.(models.ContentPreparer); ok {
+	if chunkPreparer, ok := doc.ContentType.(models.ContentChunkPreparer); ok {
+		doc.TextChunks, doc.TextChunkMetaData, err = chunkPreparer.PrepareChunks(doc.DocumentData)
+	} else if contentTyper, ok := doc.ContentType.(models.ContentPreparer); ok {
 		doc.TextChunks, err = contentTyper.Prepare(doc.DocumentData)
-		if err != nil {
-			return err
-		}
 	} else {
 		// Something has gone very wrong if the type is not set
 		// Exit here as there is no point analysing the document if the content can't be prepared
 		log.Fatal(fmt.Errorf("analyseDocument - unsupported content type: %T", doc.ContentType))
 	}
 
+	// Properties held in the document itself e.g. author and title
+	if propsReader, ok := doc.ContentType.(models.ContentPropertiesReader); ok && err == nil {
+		props, perr := propsReader.ContentProperties(doc.DocumentData)
+		if perr != nil && debug {
+			fmt.Printf("\nLaunchAnalyseDocument - DEBUG - No properties for FileName:%v - %v\n", doc.FileName, perr)
+		}
+		doc.Properties = props
+	}
+
+	// A document that can't be prepared is a warning for the document not a failure of the run
+	var warning *content.PrepareWarning
+	if errors.As(err, &warning) {
+		fmt.Printf("\nLaunchAnalyseDocument - Warning preparing FileName:%v - %v", doc.FileName, err)
+		doc.AnalysisStats.Warnings++
+		doc.AnalysisErrors = append(doc.AnalysisErrors, err)
+		return nil
+	} else if err != nil {
+		doc.AnalysisStats.Errors++
+		doc.AnalysisErrors = append(doc.AnalysisErrors, err)
+		return err
+	}
+
 	// run the document analysis
 	err = doc.contentAnalyserLauncher(debug)
 	if err != nil {
@@ -268,6 +323,22 @@ func (collection *Collection) StoreContentCatalog() error {
 	for i := range ContCat {
 		doc := &ContCat[i]
 
+		// Keep the analysis stored by the earlier run
+		if doc.Skipped {
+			doc.stored = true
+			continue
+		}
+
+		// Mark the analysis stored by the earlier run as deleted
+		if doc.Deleted {
+			err = doc.TombstoneDocumentAnalysis(collection.Conf)
+			if err != nil {
+				log.Printf("\tStoreContentCatalog - %v - Error in tombstoning FileName:%v - Error:%v\n", i, doc.FileName, err)
+			}
+			doc.stored = err == nil
+			continue
+		}
+
 		// func (doc *Document) AnalyseDocument(i int, wg *sync.WaitGroup, collection *Collection) error {
 
 		// Store the document analysis
@@ -279,12 +350,42 @@ func (collection *Collection) StoreContentCatalog() error {
 		if err != nil {
 			log.Printf("\tLaunchAnalyseDocument - %v - Error in storing FileName:%v - Error:%v\n", i, doc.FileName, err)
 		}
+		// A document that failed analysis is stored with its errors but is done again next run
+		doc.stored = err == nil && doc.AnalysisStats.Errors == 0
 
 	}
 
 	return err
 }
 
+// CommitContentCatalog - Let the collector keep its state for the next run now the catalog is stored
+// The documents whose analysis wasn't stored are passed on so the next run does them again
+func (collection *Collection) CommitContentCatalog() error {
+	committer, ok := collection.ContentSource.Collector.(models.CatalogCommitter)
+	if !ok {
+		return nil
+	}
+
+	var unstored []interface{}
+	for i := range collection.ContentCatalog {
+		doc := &collection.ContentCatalog[i]
+		if !doc.stored {
+			unstored = append(unstored, doc.ContentRef)
+		}
+	}
+
+	if collection.Conf.Debug {
+		fmt.Printf("CommitContentCatalog - DEBUG - Content Catalog:%v - %v documents not stored\n", collection.Name, len(unstored))
+	}
+
+	err := committer.CommitCatalog(unstored)
+	if err != nil {
+		return fmt.Errorf("CommitContentCatalog - Content Catalog:%v - %v", collection.Name, err)
+	}
+
+	return nil
+}
+
 // contentAnalyserLauncher - Analyse content using ContentAnalyser Interface
 // This is the effective wrapper fot calling the Analyser to process the document
 // Uses channels to communicate the results of the analysis from the analyser to the launcher
@@ -318,6 +419,16 @@ func (doc *Document) contentAnalyserLauncher(debug bool) error {
 	// This object is used to store the results of the analysis from the package
 	conAnal := analyser.NewContentAnalysis(doc.EratoContentID, doc.TextChunks)
 
+	// Give the analyser the source metadata to compare with what it extracts
+	if mds, ok := conAnal.(models.ContentMetaDataSetter); ok && len(doc.SourceMetaData) > 0 {
+		mds.SetMetaData(doc.SourceMetaData)
+	}
+
+	// and where each text chunk came from so the results can point back to it
+	if cms, ok := conAnal.(models.ContentChunkMetaDataSetter); ok && len(doc.TextChunkMetaData) > 0 {
+		cms.SetChunkMetaData(doc.TextChunkMetaData)
+	}
+
 	// Run the Document Analyser - which then
 	err = conAnal.AnalyseContent()
 	if err != nil {
@@ -414,17 +525,11 @@ func (doc *Document) RxeportDocumentAnalysisStats() {
 
 // StoreDocumentAnalysis - Save a document to the output directory
 func (doc *Document) StoreDocumentAnalysis(c *Conf) error {
-	// create a clean file name with a Unique ID to ensure that duplciate file names don't over-write each other
-	// Using the path has of the full filename path
 	if doc.Location == "" || doc.PathHash == "" || doc.FileName == "" {
 		log.Fatal("StoreDocumentAnalysis")
 	}
 
-	fileBase := strings.ReplaceAll(filepath.Base(doc.FileName), " ", "_")
-	fileBase = strings.ReplaceAll(fileBase, "/", "_")
-
-	outFn := doc.Location + "-" + doc.PathHash + "-" + fileBase + ".json"
-	outffn := filepath.Join(c.OutputDir, outFn)
+	outffn := doc.analysisFileName(c)
 
 	// open a file and wrire the contents
 
@@ -452,3 +557,58 @@ func (doc *Document) StoreDocumentAnalysis(c *Conf) error {
 
 	return nil
 }
+
+// TombstoneDocumentAnalysis - Mark the analysis stored for a document as deleted from the source
+// The analysis is kept with Deleted and TimeDeleted set so downstream stores can drop or archive it
+func (doc *Document) TombstoneDocumentAnalysis(c *Conf) error {
+	if doc.Location == "" || doc.PathHash == "" || doc.FileName == "" {
+		return fmt.Errorf("TombstoneDocumentAnalysis - Document has no Location, PathHash or FileName:%v", doc.SourceID)
+	}
+
+	outffn := doc.analysisFileName(c)
+
+	data, err := os.ReadFile(outffn)
+	if os.IsNotExist(err) {
+		// Never stored e.g. it failed analysis
+		if c.Debug {
+			fmt.Printf("TombstoneDocumentAnalysis - No stored analysis for FileName:%v\n", doc.FileName)
+		}
+		return nil
+	} else if err != nil {
+		return fmt.Errorf("TombstoneDocumentAnalysis - %v", err)
+	}
+
+	var stored map[string]interface{}
+	err = json.Unmarshal(data, &stored)
+	if err != nil {
+		return fmt.Errorf("TombstoneDocumentAnalysis - %v:%v", outffn, err)
+	}
+	stored["Deleted"] = true
+	stored["TimeDeleted"] = doc.TimeDeleted
+
+	data, err = json.MarshalIndent(stored, "", "\t")
+	if err != nil {
+		return fmt.Errorf("TombstoneDocumentAnalysis - %v", err)
+	}
+
+	if c.Debug {
+		fmt.Printf("TombstoneDocumentAnalysis - Content Catalog:%v - FileName:%v - Marking deleted:%v\n", doc.ContentSource, doc.FileName, outffn)
+	}
+
+	err = os.WriteFile(outffn, data, 0644)
+	if err != nil {
+		return fmt.Errorf("TombstoneDocumentAnalysis - %v", err)
+	}
+	return nil
+}
+
+// analysisFileName - Where the analysis of the document is stored
+// create a clean file name with a Unique ID to ensure that duplciate file names don't over-write each other
+// Using the path has of the full filename path
+func (doc *Document) analysisFileName(c *Conf) string {
+	fileBase := strings.ReplaceAll(filepath.Base(doc.FileName), " ", "_")
+	fileBase = strings.ReplaceAll(fileBase, "/", "_")
+
+	outFn := doc.Location + "-" + doc.PathHash + "-" + fileBase + ".json"
+	return filepath.Join(c.OutputDir, outFn)
+}
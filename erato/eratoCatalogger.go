@@ -7,6 +7,7 @@ import (
 	"fmt"
 	"path/filepath"
 	"strings"
+	"time"
 
 	"github.com/google/uuid"
 )
@@ -49,9 +50,11 @@ func (collection *Collection) MakeEratoContentCatalog() (ContentCatalog, error)
 		// Update the Erato doc with the file extension and file supported types
 		err = doc.UpdateType(collection)
 		if err != nil {
+			if errors.Is(err, content.ErrUnsupportedType) {
+				collection.ContentCatalogsStats.Unsupported++
+			}
 			if c.Debug {
 				fmt.Printf("MakeEratoContentCatalog - DEBUG - Warning - Document Not supported filename:%v - Error:%v\n", doc.FileName, err)
-				doc.AnalysisStats.Warnings++
 			}
 			continue
 		}
@@ -65,6 +68,25 @@ func (collection *Collection) MakeEratoContentCatalog() (ContentCatalog, error)
 			continue
 		}
 
+		// Unchanged since the last run - kept in the catalog but not analysed again
+		if tracker, ok := file.(models.ContentChangeTracker); ok && tracker.IsUnchanged() {
+			doc.Skipped = true
+			collection.ContentCatalogsStats.Skipped++
+			if c.Debug {
+				fmt.Printf("MakeEratoContentCatalog - DEBUG - Unchanged Skipped:%v\n", doc.Path)
+			}
+		}
+
+		// Deleted since the last run - kept in the catalog so the stored analysis is tombstoned
+		if tracker, ok := file.(models.ContentDeletionTracker); ok && tracker.IsDeleted() {
+			doc.Deleted = true
+			doc.TimeDeleted = time.Now()
+			collection.ContentCatalogsStats.Deleted++
+			if c.Debug {
+				fmt.Printf("MakeEratoContentCatalog - DEBUG - Deleted:%v\n", doc.FileName)
+			}
+		}
+
 		// TODO Redundent - Merge with - doc.UpdateType()
 		// On Include the supported list of extensions
 		// if doc.IncludeExtensions(c) {
@@ -84,6 +106,26 @@ func (collection *Collection) MakeEratoContentCatalog() (ContentCatalog, error)
 
 }
 
+// ExportLinkGraph - Write the link graph of the content next to the catalogue
+// when the collector has one e.g. a website
+func (collection *Collection) ExportLinkGraph() error {
+	exporter, ok := collection.ContentSource.Collector.(models.LinkGraphExporter)
+	if !ok {
+		return nil
+	}
+
+	if collection.Conf.Debug {
+		fmt.Printf("ExportLinkGraph - DEBUG - Content Catalog:%v - Writing link graph to:%v\n", collection.Name, collection.Conf.OutputDir)
+	}
+
+	err := exporter.ExportLinkGraph(collection.Conf.OutputDir, collection.Name)
+	if err != nil {
+		return fmt.Errorf("ExportLinkGraph - Content Catalog:%v - %v", collection.Name, err)
+	}
+
+	return nil
+}
+
 // filerFiles - Filter files based on the config
 func (doc *Document) FilterPath(c *Conf) bool {
 
@@ -140,6 +182,15 @@ func (doc *Document) UpdateEratoDocumentMetaData(ff interface{}, collection *Col
 	doc.Path = file.GetPath()
 	doc.PathHash = file.GetPathHash()
 	doc.ParentLocation = file.GetParentLocation()
+	doc.TimeLastModified = file.GetTimeLastModified()
+	doc.Size = file.GetSize()
+
+	if mdp, ok := ff.(models.ContentMetaDataProvider); ok {
+		doc.SourceMetaData = mdp.GetMetaData()
+	}
+	if pp, ok := ff.(models.ContentPermissionsProvider); ok {
+		doc.Permissions = pp.GetPermissions()
+	}
 
 	// TODO - add checks to ensure the key values are set
 	return err
@@ -172,6 +223,10 @@ func (doc *Document) IncludeExtensions(c *Conf) bool {
 */
 
 // UpdateType - Determines what type and establishes to then be used by the preparer
+// The preparer is the best match in the content registry for the file extension,
+// the MIME type and the start of the content when the ContentRef provides them
+// A zip based Office file with no extension or MIME type to go on matches the first Office preparer
+// until the whole of it is sniffed once it has been downloaded
 func (doc *Document) UpdateType(collection *Collection) error {
 	// return error if doc.FileName is empty
 	if doc.FileName == "" {
@@ -179,33 +234,32 @@ func (doc *Document) UpdateType(collection *Collection) error {
 	}
 
 	contRef, _ := doc.ContentRef.(models.ContentRef)
-	doc.FileExt = contRef.GetTypeName()
+	ext := strings.ToLower(contRef.GetTypeName())
+
+	if mt, ok := doc.ContentRef.(models.ContentMIMETyper); ok {
+		doc.MIMEType = mt.GetMIMEType()
+	}
+
+	var head []byte
+	if peeker, ok := doc.ContentRef.(models.ContentPeeker); ok {
+		head = peeker.PeekContent(sniffLen)
+	}
 
 	// Get the configuration of the content preparer
 	contentConfig := collection.ContentPreparer
 
 	// Now implement the concrete type to drive content Preperation
-	// Determine file type from filename extension
-	switch doc.FileExt {
-
-	case ".docx":
-		doc.FileExt = ".docx"
-		doc.ContentType = content.DOCX{Config: contentConfig}
-
-	case ".html":
-		doc.FileExt = ".html"
-		doc.ContentType = content.HTML{Config: contentConfig}
-
-	case ".pdf":
-		// Convert from PDF to text
-		// doc.FileExt = ".pdf"
-		// doc.ContentType = content.PDF{Config: contentConfig}
-
-		return fmt.Errorf("unsupported file type: %v", doc.FileExt)
-	default:
-		return fmt.Errorf("unsupported file type: %v", doc.FileExt)
+	reg, err := content.Match(ext, doc.MIMEType, head)
+	if err != nil {
+		return fmt.Errorf("UpdateType - %w: extension=%q MIME type=%q", err, ext, doc.MIMEType)
 	}
 
+	doc.FileExt = reg.FileExt
+	doc.ContentType = reg.New(contentConfig)
+
 	return nil
 
 }
+
+// sniffLen - How much of the content is checked for a signature
+const sniffLen = 512
@@ -1,6 +1,7 @@
 package erato
 
 import (
+	filesystem "Erato/erato/collectors/filesystem"
 	sharepoint "Erato/erato/collectors/sharepoint"
 	website "Erato/erato/collectors/website"
 	"Erato/erato/models"
@@ -14,6 +15,7 @@ func (coll *Collection) ContentCollector() models.Collector {
 type EratoCollectors struct {
 	Sharepoint *sharepoint.SharePointColector
 	Website    *website.WebsiteCollector
+	Filesystem *filesystem.FilesystemCollector
 }
 
 // Where and how to get the data
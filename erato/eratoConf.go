@@ -2,12 +2,14 @@ package erato
 
 import (
 	"Erato/erato/analysers/openai"
+	filesystem "Erato/erato/collectors/filesystem"
 	sharepoint "Erato/erato/collectors/sharepoint"
 	website "Erato/erato/collectors/website"
 	content "Erato/erato/preparers/content"
 	"Erato/erato/utils"
 	"fmt"
 	"os"
+	"path/filepath"
 	"strconv"
 	"strings"
 
@@ -17,55 +19,138 @@ import (
 // STRUCTURES
 // Config - Configuration for the Erato Application
 type EratoConf struct {
-	Conf       Conf2          `yaml:"Conf"`
-	Collectors CollectorsConf `yaml:"Collectors"`
-	Analysers  AnalysersConf  `yaml:"Analysers"`
-	Preparer   PreparerConf   `yaml:"Preparer"`
+	Conf        Conf2            `yaml:"Conf"`
+	Collectors  CollectorsConf   `yaml:"Collectors"`
+	Analysers   AnalysersConf    `yaml:"Analysers"`
+	Preparers   []PreparerConf   `yaml:"Preparers"`
+	Collections []CollectionConf `yaml:"Collections"`
 }
 
 type Conf2 struct {
+	Env             string   `yaml:"Env"`
 	Debug           bool     `yaml:"Debug"`
 	ExcludePaths    []string `yaml:"ExcludePaths"`
 	AuditDir        string   `yaml:"AuditDir"`
 	LogDir          string   `yaml:"LogDir"`
+	OutputDir       string   `yaml:"OutputDir"`
 	AnalysisWorkers int      `yaml:"AnalysisWorkers"`
 	DepthLimit      int      `yaml:"DepthLimit"`
 }
 
+// CollectorsConf - Each collector is declared with a unique Name
+// which the Collections use to refer to it
 type CollectorsConf struct {
-	Sharepoint SharepointConf `yaml:"Sharepoint"`
-	Website    WebsiteConf    `yaml:"Website"`
+	Sharepoints []SharepointConf `yaml:"Sharepoints"`
+	Websites    []WebsiteConf    `yaml:"Websites"`
+	Filesystems []FilesystemConf `yaml:"Filesystems"`
 }
 
 type SharepointConf struct {
 	Name        string `yaml:"Name"`
 	SecretsFile string `yaml:"SecretsFile"`
 	SiteUrl     string `yaml:"SiteUrl"`
-	DebugDepth  int    `yaml:"DebugDepth"`
-	Debug       bool   `yaml:"Debug"`
+	DepthLimit  int    `yaml:"DepthLimit"`
+	// AuthStrategy - azurecert (default), addin, device or ntlm, the SecretsFile has the credentials for it
+	AuthStrategy string `yaml:"AuthStrategy"`
+	// SubSiteDepth - Levels of subsites to catalog with the site, 0 for none
+	SubSiteDepth int `yaml:"SubSiteDepth"`
+	// Libraries to catalog and skip - names or globs e.g. "Project *"
+	Libraries        []string `yaml:"Libraries"`
+	ExcludeLibraries []string `yaml:"ExcludeLibraries"`
+	// File filters sent to SharePoint - ModifiedAfter is 2006-01-02, the sizes are in KB
+	ModifiedAfter string   `yaml:"ModifiedAfter"`
+	MinFileSize   int64    `yaml:"MinFileSize"`
+	MaxFileSize   int64    `yaml:"MaxFileSize"`
+	ContentTypes  []string `yaml:"ContentTypes"`
+	// Columns - List item columns to capture with each file, internal or display names
+	Columns []string `yaml:"Columns"`
+	// Permissions - Capture the users and groups that can read each file
+	Permissions bool `yaml:"Permissions"`
+	// StateDir - Keeps the change token of each library so later runs only catalog the changes
+	StateDir string `yaml:"StateDir"`
+	Debug    bool   `yaml:"Debug"`
 }
 
 type WebsiteConf struct {
 	Name           string `yaml:"Name"`
+	SiteUrl        string `yaml:"SiteUrl"`
 	AllowedDomains string `yaml:"AllowedDomains"`
+	MaxDepth       int    `yaml:"MaxDepth"`
+	// Main content extraction - CSS selectors for the content to keep and the elements to remove
+	IncludeSelectors []string `yaml:"IncludeSelectors"`
+	ExcludeSelectors []string `yaml:"ExcludeSelectors"`
+	// Blocks of text on at least this many pages are removed, 0 keeps them
+	RepeatedBlockPages int  `yaml:"RepeatedBlockPages"`
+	KeepFullPage       bool `yaml:"KeepFullPage"`
+	// Crawl politeness - an honest UserAgent, robots.txt and limits for each domain
+	UserAgent       string `yaml:"UserAgent"`
+	IgnoreRobotsTxt bool   `yaml:"IgnoreRobotsTxt"`
+	Parallelism     int    `yaml:"Parallelism"`
+	// Delay and RandomDelay between requests in milliseconds
+	Delay       int `yaml:"Delay"`
+	RandomDelay int `yaml:"RandomDelay"`
+	// Sitemaps to seed the crawl - robots.txt and /sitemap.xml are tried when not set
+	Sitemaps     []string `yaml:"Sitemaps"`
+	SkipSitemaps bool     `yaml:"SkipSitemaps"`
+	// URL patterns to crawl and to skip - globs or regular expressions starting re:
+	IncludeURLs []string `yaml:"IncludeURLs"`
+	ExcludeURLs []string `yaml:"ExcludeURLs"`
+	// Query parameters removed from links as well as the tracking ones
+	StripQueryParams []string `yaml:"StripQueryParams"`
+	// add or remove the trailing slash of paths
+	TrailingSlash   string `yaml:"TrailingSlash"`
+	IgnoreCanonical bool   `yaml:"IgnoreCanonical"`
+	// Linked documents e.g. PDFs are downloaded up to MaxDocumentSize in MB
+	SkipDocuments   bool `yaml:"SkipDocuments"`
+	MaxDocumentSize int  `yaml:"MaxDocumentSize"`
+	// Sign in to intranets and portals
+	Auth WebsiteAuthConf `yaml:"Auth"`
+	// Directory for the crawl state and page bodies so an interrupted crawl resumes
+	// and unchanged pages are skipped on the next crawl
+	StateDir string `yaml:"StateDir"`
+	Debug    bool   `yaml:"Debug"`
+}
+
+// WebsiteAuthConf - The BEARER_TOKEN, USERNAME and PASSWORD are kept in the .env style SecretsFile
+type WebsiteAuthConf struct {
+	SecretsFile string `yaml:"SecretsFile"`
+	// Sent with every request, ${NAME} is replaced with NAME from the SecretsFile
+	Headers map[string]string `yaml:"Headers"`
+	// Netscape cookies.txt exported from a browser
+	CookieFile string `yaml:"CookieFile"`
+	// Form login before the crawl and when the session expires
+	LoginURL      string            `yaml:"LoginURL"`
+	UsernameField string            `yaml:"UsernameField"`
+	PasswordField string            `yaml:"PasswordField"`
+	LoginFields   map[string]string `yaml:"LoginFields"`
+}
+
+type FilesystemConf struct {
+	Name           string `yaml:"Name"`
+	RootDir        string `yaml:"RootDir"`
+	DepthLimit     int    `yaml:"DepthLimit"`
+	FollowSymlinks bool   `yaml:"FollowSymlinks"`
+	ExpandArchives bool   `yaml:"ExpandArchives"`
 	Debug          bool   `yaml:"Debug"`
 }
 
 type AnalysersConf struct {
-	OpenAI            OpenAIConf            `yaml:"OpenAI"`
-	ComprehendMedical ComprehendMedicalConf `yaml:"ComprehendMedical"`
+	OpenAI            []OpenAIConf            `yaml:"OpenAI"`
+	ComprehendMedical []ComprehendMedicalConf `yaml:"ComprehendMedical"`
 }
 
 type OpenAIConf struct {
-	Name        string `yaml:"Name"`
-	BaseURL     string `yaml:"BaseURL"`
-	SecretsFile string `yaml:"SecretsFile"`
-	Model       string `yaml:"Model"`
-	MaxTokens   int    `yaml:"MaxTokens"`
-	Temp        int    `yaml:"Temp"`
-	Workers     int    `yaml:"Workers"`
-	PromptFile  string `yaml:"PromptFile"`
-	Debug       bool   `yaml:"Debug"`
+	Name        string  `yaml:"Name"`
+	BaseURL     string  `yaml:"BaseURL"`
+	SecretsFile string  `yaml:"SecretsFile"`
+	Model       string  `yaml:"Model"`
+	MaxTokens   int     `yaml:"MaxTokens"`
+	Temp        float32 `yaml:"Temp"`
+	Workers     int     `yaml:"Workers"`
+	WorkerDelay int     `yaml:"WorkerDelay"`
+	PromptFile  string  `yaml:"PromptFile"` // {{MetaData}} in the prompt is replaced with the source metadata of the document, without it the metadata is added at the end
+	Disable     bool    `yaml:"Disable"`
+	Debug       bool    `yaml:"Debug"`
 }
 
 type ComprehendMedicalConf struct {
@@ -76,44 +161,81 @@ type ComprehendMedicalConf struct {
 }
 
 type PreparerConf struct {
-	MaxParagraphWordCount int  `yaml:"MaxParagraphWordCount"`
-	MinParagraphWordCount int  `yaml:"MinParagraphWordCount"`
-	Debug                 bool `yaml:"Debug"`
+	Name                  string `yaml:"Name"`
+	MaxParagraphWordCount int    `yaml:"MaxParagraphWordCount"`
+	MinParagraphWordCount int    `yaml:"MinParagraphWordCount"`
+	// Chunker - words (default) or tokens counted with the tokenizer of the Collection's Analyser Model
+	Chunker                string `yaml:"Chunker"`
+	MaxChunkTokenCount     int    `yaml:"MaxChunkTokenCount"`
+	ChunkOverlapTokenCount int    `yaml:"ChunkOverlapTokenCount"`
+	Debug                  bool   `yaml:"Debug"`
 }
 
+// CollectionConf - Ties a collector, preparer and analyser together by their Names
+type CollectionConf struct {
+	Name      string `yaml:"Name"`
+	Collector string `yaml:"Collector"`
+	Preparer  string `yaml:"Preparer"`
+	Analyser  string `yaml:"Analyser"`
+}
+
+// NewConf2 - Read the YML config file
+// cf is either a path to the file or a config name to search for in the config paths
 func NewConf2(cf string) (*EratoConf, error) {
 	var err error
 	var conf EratoConf
 
 	v := viper.New()
-	v.SetConfigName(cf)
-	v.SetConfigType("yml")
 
-	// Set the path to look for the configurations file
-	v.AddConfigPath("./config/")
-	v.AddConfigPath("./secrets/")
-	v.AddConfigPath("./")
+	if filepath.Ext(cf) != "" {
+		v.SetConfigFile(cf)
+	} else {
+		v.SetConfigName(cf)
+		v.SetConfigType("yml")
+
+		// Set the path to look for the configurations file
+		v.AddConfigPath("./conf/")
+		v.AddConfigPath("./config/")
+		v.AddConfigPath("./secrets/")
+		v.AddConfigPath("./")
+	}
 
 	err = v.ReadInConfig()
 	if err != nil {
 		return nil, fmt.Errorf("error reading config file %v,Error=%s", cf, err)
 	}
 
-	err = v.Unmarshal(conf)
+	// All the settings sit under the top level Erato key
+	err = v.UnmarshalKey("Erato", &conf)
 	if err != nil {
 		return nil, fmt.Errorf("unable to Unmarshall configfile into struct, %v", err)
 	}
 
-	// Add value checker
-	// err = CheckConfig("GlobalConf", gc)
-	// if err != nil {
-	// 	return err
-	// }
+	// Check all the values so every problem is reported at once
+	err = CheckConfig("Erato", &conf)
+	if err != nil {
+		return nil, err
+	}
 
 	return &conf, err
 
 }
 
+// GlobalConf - Map the YML global settings to the Conf used by the collections
+func (ec *EratoConf) GlobalConf() *Conf {
+	c2 := ec.Conf
+
+	return &Conf{
+		Env:                  c2.Env,
+		ExcludedPath:         c2.ExcludePaths,
+		LogDir:               c2.LogDir,
+		AuditDir:             c2.AuditDir,
+		OutputDir:            c2.OutputDir,
+		EratoAnalysisWorkers: c2.AnalysisWorkers,
+		Debug:                c2.Debug,
+	}
+}
+
 type Conf struct {
 	Env                    string
 	ExcludedPath           []string
@@ -127,60 +249,44 @@ type Conf struct {
 	// To be depricated
 	SharePoint      sharepoint.SharePointConfig
 	Website         website.WebsiteConfig
+	Filesystem      filesystem.FilesystemConfig
 	ContentPreparer content.Config
 	XX_OAI          openai.Config
 }
 
-// NewConfig - Create a new config struct
-func NewConfig() *Conf {
-
-	// TODO - Bolt this back in
-	// err := checkEnvVars(EnvVars)
-	// if err != nil {
-	// 	panic(err)
-	// }
+// EnvVars - The environment variables NewConfig requires
+const EnvVars = "PARAGRAPH_MAX_WORD_COUNT,PARAGRAPH_MIN_WORD_COUNT,LEVEL_LIMIT," +
+	"OPENAI_WORKERS,ERATO_ANALYSIS_WORKERS,OPENAI_MAX_TOKENS,OPENAI_SLEEP,OPENAI_TEMP," +
+	"OPENAI_BASE,OPENAI_MODEL,PROMPT_EXAMPLE_FILE"
 
-	// convert a string to an int
-	max, err := strconv.Atoi(os.Getenv("PARAGRAPH_MAX_WORD_COUNT"))
-	if err != nil {
-		panic(err)
-	}
-	min, err := strconv.Atoi(os.Getenv("PARAGRAPH_MIN_WORD_COUNT"))
-	if err != nil {
-		panic(err)
-	}
+// NewConfig - Create a new config struct
+func NewConfig() (*Conf, error) {
+	var ces ConfigErrors
 
-	levelLimit, err := strconv.Atoi(os.Getenv("LEVEL_LIMIT"))
+	err := utils.CheckEnvVars(EnvVars)
 	if err != nil {
-		panic(err)
+		return nil, err
 	}
 
-	oaiParralelReq, err := strconv.Atoi(os.Getenv("OPENAI_WORKERS"))
-	if err != nil {
-		panic(err)
-	}
+	// convert a string to an int
+	max := envInt("PARAGRAPH_MAX_WORD_COUNT", &ces)
+	min := envInt("PARAGRAPH_MIN_WORD_COUNT", &ces)
+	levelLimit := envInt("LEVEL_LIMIT", &ces)
+	oaiParralelReq := envInt("OPENAI_WORKERS", &ces)
 	// Get the int value for the setting
-	eratoAnalsysisWorkers, err := strconv.Atoi(os.Getenv("ERATO_ANALYSIS_WORKERS"))
-	if err != nil {
-		panic(err)
-	}
-
-	oaiMaxTokens, err := strconv.Atoi(os.Getenv("OPENAI_MAX_TOKENS"))
-	if err != nil {
-		panic(err)
-	}
-
-	oaiWorkerDelay, err := strconv.Atoi(os.Getenv("OPENAI_SLEEP"))
-	if err != nil {
-		panic(err)
-	}
+	eratoAnalsysisWorkers := envInt("ERATO_ANALYSIS_WORKERS", &ces)
+	oaiMaxTokens := envInt("OPENAI_MAX_TOKENS", &ces)
+	oaiWorkerDelay := envInt("OPENAI_SLEEP", &ces)
 
 	// oaiTemp, err := strconv.Atoi(os.Getenv("OPENAI_TEMP"))
 	oaiTemp, err := strconv.ParseFloat(os.Getenv("OPENAI_TEMP"), 32)
 	if err != nil {
-		panic(err)
+		ces.add("OPENAI_TEMP", os.Getenv("OPENAI_TEMP"), "is not a number")
 	}
 
+	// The prompt file is checked by CheckConfig
+	prompt, _ := os.ReadFile(os.Getenv("PROMPT_EXAMPLE_FILE"))
+
 	debug := utils.StringToBool(os.Getenv("DEBUG"))
 
 	oiac := openai.Config{
@@ -193,16 +299,17 @@ func NewConfig() *Conf {
 		OAIexampleFile:      os.Getenv("PROMPT_EXAMPLE_FILE"),
 		OAIparralelRequests: oaiParralelReq,
 		OpenAIworkerDelay:   oaiWorkerDelay,
-		OIAprompt:           utils.Prompt(os.Getenv("PROMPT_EXAMPLE_FILE")),
+		OIAprompt:           string(prompt),
 		Debug:               debug,
 	}
 
 	spc := sharepoint.SharePointConfig{
-		SPdepthLimit: levelLimit,
-		SPsiteURL:    os.Getenv("SP_SITE_URL"),
-		SPsiteName:   os.Getenv("SP_SITE_NAME"),
-		SPAuthFile:   os.Getenv("SP_AUTH_FILE"),
-		Debug:        debug,
+		SPdepthLimit:   levelLimit,
+		SPsiteURL:      os.Getenv("SP_SITE_URL"),
+		SPsiteName:     os.Getenv("SP_SITE_NAME"),
+		SPAuthFile:     os.Getenv("SP_AUTH_FILE"),
+		SPauthStrategy: os.Getenv("SP_AUTH_STRATEGY"),
+		Debug:          debug,
 	}
 
 	web := website.WebsiteConfig{
@@ -212,6 +319,15 @@ func NewConfig() *Conf {
 		Debug:          debug,
 	}
 
+	fs := filesystem.FilesystemConfig{
+		Name:           os.Getenv("FILESYSTEM_NAME"),
+		RootDir:        os.Getenv("FILESYSTEM_ROOT_DIR"),
+		DepthLimit:     levelLimit,
+		FollowSymlinks: utils.StringToBool(os.Getenv("FILESYSTEM_FOLLOW_SYMLINKS")),
+		ExpandArchives: utils.StringToBool(os.Getenv("FILESYSTEM_EXPAND_ARCHIVES")),
+		Debug:          debug,
+	}
+
 	cp := content.Config{
 		ParagraphMaxWordCount: max,
 		ParagraphMinWordCount: min,
@@ -219,6 +335,7 @@ func NewConfig() *Conf {
 	}
 
 	ExcludedPath := strings.Split(os.Getenv("EXCLUDED_PATHS"), ",")
+	fs.ExcludedPath = ExcludedPath
 	IncludedFileExtensions := strings.Split(os.Getenv("INCLUDED_FILE_EXTENSIONS"), ",")
 
 	c := Conf{
@@ -231,19 +348,28 @@ func NewConfig() *Conf {
 		XX_OAI:                 oiac,
 		SharePoint:             spc,
 		Website:                web,
+		Filesystem:             fs,
 		ContentPreparer:        cp,
 		EratoAnalysisWorkers:   eratoAnalsysisWorkers,
 		Debug:                  debug,
 	}
 
-	return &c
-}
-
-// TODO
-// CheckConfig - Check the config values
-func CheckConfig(name string, c interface{}) error {
-	var err error
+	// Report the values that couldn't be converted along with the rest of the problems
+	err = CheckConfig("Conf", &c)
+	if cerrs, ok := err.(ConfigErrors); ok {
+		ces = append(ces, cerrs...)
+	} else if err != nil {
+		return nil, err
+	}
 
-	return err
+	return &c, ces.err()
+}
 
+// envInt - Convert an environment variable to an int recording the problem if it isn't one
+func envInt(name string, ces *ConfigErrors) int {
+	v, err := strconv.Atoi(os.Getenv(name))
+	if err != nil {
+		ces.add(name, os.Getenv(name), "is not a whole number")
+	}
+	return v
 }
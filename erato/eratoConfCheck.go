@@ -0,0 +1,427 @@
+package erato
+
+import (
+	sharepoint "Erato/erato/collectors/sharepoint"
+	"Erato/erato/collectors/website"
+	content "Erato/erato/preparers/content"
+	"Erato/erato/preparers/readability"
+	"fmt"
+	"net/url"
+	"os"
+	"strings"
+)
+
+// ConfigError - A problem found with a single config setting
+type ConfigError struct {
+	Field   string
+	Value   interface{}
+	Problem string
+}
+
+func (ce ConfigError) Error() string {
+	return fmt.Sprintf("%v: %v (value=%v)", ce.Field, ce.Problem, ce.Value)
+}
+
+// ConfigErrors - All the problems found in a config so they can be fixed in one go
+type ConfigErrors []ConfigError
+
+func (ces ConfigErrors) Error() string {
+	var lines []string
+	for _, ce := range ces {
+		lines = append(lines, "\t"+ce.Error())
+	}
+	return fmt.Sprintf("config has %v problem(s):\n%v", len(ces), strings.Join(lines, "\n"))
+}
+
+// add - Record a problem with a field
+func (ces *ConfigErrors) add(field string, value interface{}, problem string) {
+	*ces = append(*ces, ConfigError{Field: field, Value: value, Problem: problem})
+}
+
+// err - Return nil rather than an empty ConfigErrors
+func (ces ConfigErrors) err() error {
+	if len(ces) == 0 {
+		return nil
+	}
+	return ces
+}
+
+// CheckConfig - Check the config values
+// Returns ConfigErrors listing every problem found
+func CheckConfig(name string, c interface{}) error {
+	var ces ConfigErrors
+
+	switch conf := c.(type) {
+	case *EratoConf:
+		checkEratoConf(name, conf, &ces)
+	case *Conf:
+		checkConf(name, conf, &ces)
+	default:
+		return fmt.Errorf("CheckConfig - %v - unsupported config type: %T", name, c)
+	}
+
+	return ces.err()
+}
+
+// checkEratoConf - Check the YML config
+func checkEratoConf(name string, ec *EratoConf, ces *ConfigErrors) {
+	f := func(field string, a ...interface{}) string {
+		return name + "." + fmt.Sprintf(field, a...)
+	}
+
+	// Global settings
+	c2 := ec.Conf
+	if c2.AnalysisWorkers <= 0 {
+		ces.add(f("Conf.AnalysisWorkers"), c2.AnalysisWorkers, "must be greater than 0")
+	}
+	if c2.DepthLimit < 0 {
+		ces.add(f("Conf.DepthLimit"), c2.DepthLimit, "must be 0 (no limit) or greater")
+	}
+	if c2.OutputDir == "" {
+		ces.add(f("Conf.OutputDir"), c2.OutputDir, "is required")
+	} else {
+		checkDir(f("Conf.OutputDir"), c2.OutputDir, ces)
+	}
+
+	// Collectors - the Names are shared across the collector types
+	collectors := make(map[string]bool)
+	checkName := func(field string, name string) {
+		if name == "" {
+			ces.add(field, name, "is required")
+			return
+		}
+		if collectors[name] {
+			ces.add(field, name, "is a duplicate Collector Name")
+		}
+		collectors[name] = true
+	}
+
+	for i, sp := range ec.Collectors.Sharepoints {
+		checkName(f("Collectors.Sharepoints[%v].Name", i), sp.Name)
+		checkURL(f("Collectors.Sharepoints[%v].SiteUrl", i), sp.SiteUrl, ces)
+		checkFile(f("Collectors.Sharepoints[%v].SecretsFile", i), sp.SecretsFile, ces)
+		if err := sharepoint.AuthStrategy(sp.AuthStrategy); err != nil {
+			ces.add(f("Collectors.Sharepoints[%v].AuthStrategy", i), sp.AuthStrategy, "must be one of "+strings.Join(sharepoint.AuthStrategies, ", "))
+		}
+		if sp.DepthLimit < 0 {
+			ces.add(f("Collectors.Sharepoints[%v].DepthLimit", i), sp.DepthLimit, "must be 0 (no limit) or greater")
+		}
+		if sp.SubSiteDepth < 0 {
+			ces.add(f("Collectors.Sharepoints[%v].SubSiteDepth", i), sp.SubSiteDepth, "must be 0 (no subsites) or greater")
+		}
+		for j, l := range sp.Libraries {
+			if err := sharepoint.LibraryPattern(l); err != nil {
+				ces.add(f("Collectors.Sharepoints[%v].Libraries[%v]", i, j), l, "is not a valid library name or glob")
+			}
+		}
+		for j, l := range sp.ExcludeLibraries {
+			if err := sharepoint.LibraryPattern(l); err != nil {
+				ces.add(f("Collectors.Sharepoints[%v].ExcludeLibraries[%v]", i, j), l, "is not a valid library name or glob")
+			}
+		}
+		if _, err := sharepoint.ParseDate(sp.ModifiedAfter); err != nil {
+			ces.add(f("Collectors.Sharepoints[%v].ModifiedAfter", i), sp.ModifiedAfter, "is not a date 2006-01-02 or time 2006-01-02T15:04:05Z")
+		}
+		if sp.MinFileSize < 0 {
+			ces.add(f("Collectors.Sharepoints[%v].MinFileSize", i), sp.MinFileSize, "must be 0 (no minimum) or greater")
+		}
+		if sp.MaxFileSize < 0 {
+			ces.add(f("Collectors.Sharepoints[%v].MaxFileSize", i), sp.MaxFileSize, "must be 0 (no maximum) or greater")
+		} else if sp.MaxFileSize > 0 && sp.MaxFileSize < sp.MinFileSize {
+			ces.add(f("Collectors.Sharepoints[%v].MaxFileSize", i), sp.MaxFileSize, "must be greater than the MinFileSize")
+		}
+		for j, col := range sp.Columns {
+			if strings.TrimSpace(col) == "" {
+				ces.add(f("Collectors.Sharepoints[%v].Columns[%v]", i, j), col, "is blank")
+			}
+		}
+		if sp.StateDir != "" {
+			if fi, err := os.Stat(sp.StateDir); err == nil && !fi.IsDir() {
+				ces.add(f("Collectors.Sharepoints[%v].StateDir", i), sp.StateDir, "is not a directory")
+			}
+		}
+	}
+
+	for i, web := range ec.Collectors.Websites {
+		checkName(f("Collectors.Websites[%v].Name", i), web.Name)
+		checkURL(f("Collectors.Websites[%v].SiteUrl", i), web.SiteUrl, ces)
+		if strings.TrimSpace(web.AllowedDomains) == "" {
+			ces.add(f("Collectors.Websites[%v].AllowedDomains", i), web.AllowedDomains, "is required")
+		}
+		if web.MaxDepth < 0 {
+			ces.add(f("Collectors.Websites[%v].MaxDepth", i), web.MaxDepth, "must be 0 (no limit) or greater")
+		}
+		if web.Parallelism < 0 {
+			ces.add(f("Collectors.Websites[%v].Parallelism", i), web.Parallelism, "must be 0 (one at a time) or greater")
+		}
+		if web.Delay < 0 {
+			ces.add(f("Collectors.Websites[%v].Delay", i), web.Delay, "must be 0 or greater")
+		}
+		if web.RandomDelay < 0 {
+			ces.add(f("Collectors.Websites[%v].RandomDelay", i), web.RandomDelay, "must be 0 or greater")
+		}
+		if web.MaxDocumentSize < 0 {
+			ces.add(f("Collectors.Websites[%v].MaxDocumentSize", i), web.MaxDocumentSize, "must be 0 (the default size) or greater")
+		}
+		if web.Auth.SecretsFile != "" {
+			checkFile(f("Collectors.Websites[%v].Auth.SecretsFile", i), web.Auth.SecretsFile, ces)
+		}
+		if web.Auth.CookieFile != "" {
+			checkFile(f("Collectors.Websites[%v].Auth.CookieFile", i), web.Auth.CookieFile, ces)
+		}
+		if web.Auth.LoginURL != "" {
+			checkURL(f("Collectors.Websites[%v].Auth.LoginURL", i), web.Auth.LoginURL, ces)
+			if web.Auth.SecretsFile == "" {
+				ces.add(f("Collectors.Websites[%v].Auth.SecretsFile", i), web.Auth.SecretsFile, "is required for the LoginURL credentials")
+			}
+			if web.Auth.UsernameField == "" {
+				ces.add(f("Collectors.Websites[%v].Auth.UsernameField", i), web.Auth.UsernameField, "is required with a LoginURL")
+			}
+			if web.Auth.PasswordField == "" {
+				ces.add(f("Collectors.Websites[%v].Auth.PasswordField", i), web.Auth.PasswordField, "is required with a LoginURL")
+			}
+		}
+		for j, sm := range web.Sitemaps {
+			checkURL(f("Collectors.Websites[%v].Sitemaps[%v]", i, j), sm, ces)
+		}
+		for j, pattern := range web.IncludeURLs {
+			if _, err := website.URLPattern(pattern); err != nil {
+				ces.add(f("Collectors.Websites[%v].IncludeURLs[%v]", i, j), pattern, fmt.Sprintf("is not a valid pattern: %v", err))
+			}
+		}
+		for j, pattern := range web.ExcludeURLs {
+			if _, err := website.URLPattern(pattern); err != nil {
+				ces.add(f("Collectors.Websites[%v].ExcludeURLs[%v]", i, j), pattern, fmt.Sprintf("is not a valid pattern: %v", err))
+			}
+		}
+		switch web.TrailingSlash {
+		case "", website.TrailingSlashAdd, website.TrailingSlashRemove:
+		default:
+			ces.add(f("Collectors.Websites[%v].TrailingSlash", i), web.TrailingSlash,
+				fmt.Sprintf("must be %v, %v or not set", website.TrailingSlashAdd, website.TrailingSlashRemove))
+		}
+		if web.StateDir != "" {
+			if fi, err := os.Stat(web.StateDir); err == nil && !fi.IsDir() {
+				ces.add(f("Collectors.Websites[%v].StateDir", i), web.StateDir, "is not a directory")
+			}
+		}
+		if web.RepeatedBlockPages < 0 {
+			ces.add(f("Collectors.Websites[%v].RepeatedBlockPages", i), web.RepeatedBlockPages, "must be 0 (keep repeated blocks) or greater")
+		}
+		for j, sel := range web.IncludeSelectors {
+			if err := readability.CheckSelector(sel); err != nil {
+				ces.add(f("Collectors.Websites[%v].IncludeSelectors[%v]", i, j), sel, fmt.Sprintf("is not a valid CSS selector: %v", err))
+			}
+		}
+		for j, sel := range web.ExcludeSelectors {
+			if err := readability.CheckSelector(sel); err != nil {
+				ces.add(f("Collectors.Websites[%v].ExcludeSelectors[%v]", i, j), sel, fmt.Sprintf("is not a valid CSS selector: %v", err))
+			}
+		}
+	}
+
+	for i, fs := range ec.Collectors.Filesystems {
+		checkName(f("Collectors.Filesystems[%v].Name", i), fs.Name)
+		if fs.RootDir == "" {
+			ces.add(f("Collectors.Filesystems[%v].RootDir", i), fs.RootDir, "is required")
+		} else {
+			checkDir(f("Collectors.Filesystems[%v].RootDir", i), fs.RootDir, ces)
+		}
+		if fs.DepthLimit < 0 {
+			ces.add(f("Collectors.Filesystems[%v].DepthLimit", i), fs.DepthLimit, "must be 0 (no limit) or greater")
+		}
+	}
+
+	// Analysers
+	analysers := make(map[string]bool)
+	for i, oai := range ec.Analysers.OpenAI {
+		field := f("Analysers.OpenAI[%v]", i)
+		if oai.Name == "" {
+			ces.add(field+".Name", oai.Name, "is required")
+		} else if analysers[oai.Name] {
+			ces.add(field+".Name", oai.Name, "is a duplicate Analyser Name")
+		}
+		analysers[oai.Name] = true
+
+		checkURL(field+".BaseURL", oai.BaseURL, ces)
+		checkFile(field+".SecretsFile", oai.SecretsFile, ces)
+		checkFile(field+".PromptFile", oai.PromptFile, ces)
+		if oai.Model == "" {
+			ces.add(field+".Model", oai.Model, "is required")
+		}
+		if oai.MaxTokens <= 0 {
+			ces.add(field+".MaxTokens", oai.MaxTokens, "must be greater than 0")
+		}
+		if oai.Temp < 0 || oai.Temp > 2 {
+			ces.add(field+".Temp", oai.Temp, "must be between 0 and 2")
+		}
+		if oai.Workers <= 0 {
+			ces.add(field+".Workers", oai.Workers, "must be greater than 0")
+		}
+		if oai.WorkerDelay < 0 {
+			ces.add(field+".WorkerDelay", oai.WorkerDelay, "must be 0 or greater")
+		}
+	}
+
+	// ComprehendMedical is in the config model but there is no analyser for it yet
+	for i, cm := range ec.Analysers.ComprehendMedical {
+		ces.add(f("Analysers.ComprehendMedical[%v].Name", i), cm.Name, "ComprehendMedical analysers are not supported yet")
+	}
+
+	// Preparers
+	preparers := make(map[string]bool)
+	for i, pc := range ec.Preparers {
+		field := f("Preparers[%v]", i)
+		if pc.Name == "" {
+			ces.add(field+".Name", pc.Name, "is required")
+		} else if preparers[pc.Name] {
+			ces.add(field+".Name", pc.Name, "is a duplicate Preparer Name")
+		}
+		preparers[pc.Name] = true
+
+		switch pc.Chunker {
+		case "", content.ChunkerWords:
+			checkWordCounts(field+".MinParagraphWordCount", pc.MinParagraphWordCount, field+".MaxParagraphWordCount", pc.MaxParagraphWordCount, ces)
+		case content.ChunkerTokens:
+			if pc.MinParagraphWordCount < 0 {
+				ces.add(field+".MinParagraphWordCount", pc.MinParagraphWordCount, "must be 0 or greater")
+			}
+			if pc.MaxChunkTokenCount <= 0 {
+				ces.add(field+".MaxChunkTokenCount", pc.MaxChunkTokenCount, "must be greater than 0")
+			}
+			if pc.ChunkOverlapTokenCount < 0 || pc.ChunkOverlapTokenCount >= pc.MaxChunkTokenCount {
+				ces.add(field+".ChunkOverlapTokenCount", pc.ChunkOverlapTokenCount, fmt.Sprintf("must be 0 or greater and less than MaxChunkTokenCount (%v)", pc.MaxChunkTokenCount))
+			}
+		default:
+			ces.add(field+".Chunker", pc.Chunker, fmt.Sprintf("must be %v or %v", content.ChunkerWords, content.ChunkerTokens))
+		}
+	}
+
+	// Collections must refer to the declared parts
+	if len(ec.Collections) == 0 {
+		ces.add(f("Collections"), len(ec.Collections), "at least one Collection is required")
+	}
+	for i, cc := range ec.Collections {
+		field := f("Collections[%v]", i)
+		if cc.Name == "" {
+			ces.add(field+".Name", cc.Name, "is required")
+		}
+		if !collectors[cc.Collector] {
+			ces.add(field+".Collector", cc.Collector, "does not match a Collector Name")
+		}
+		if !preparers[cc.Preparer] {
+			ces.add(field+".Preparer", cc.Preparer, "does not match a Preparer Name")
+		}
+		if !analysers[cc.Analyser] {
+			ces.add(field+".Analyser", cc.Analyser, "does not match an Analyser Name")
+		}
+	}
+}
+
+// checkConf - Check the config created from the environment variables
+func checkConf(name string, c *Conf, ces *ConfigErrors) {
+	f := func(field string) string {
+		return name + "." + field
+	}
+
+	if c.EratoAnalysisWorkers <= 0 {
+		ces.add(f("EratoAnalysisWorkers"), c.EratoAnalysisWorkers, "must be greater than 0")
+	}
+
+	checkWordCounts(f("ContentPreparer.ParagraphMinWordCount"), c.ContentPreparer.ParagraphMinWordCount,
+		f("ContentPreparer.ParagraphMaxWordCount"), c.ContentPreparer.ParagraphMaxWordCount, ces)
+
+	// OpenAI
+	oai := c.XX_OAI
+	checkURL(f("XX_OAI.OAIapibase"), oai.OAIapibase, ces)
+	checkFile(f("XX_OAI.OAIexampleFile"), oai.OAIexampleFile, ces)
+	if oai.OAIapiKey == "" && !oai.OAIdisable {
+		ces.add(f("XX_OAI.OAIapiKey"), "", "is required")
+	}
+	if oai.OAImaxTokens <= 0 {
+		ces.add(f("XX_OAI.OAImaxTokens"), oai.OAImaxTokens, "must be greater than 0")
+	}
+	if oai.OAItemperature < 0 || oai.OAItemperature > 2 {
+		ces.add(f("XX_OAI.OAItemperature"), oai.OAItemperature, "must be between 0 and 2")
+	}
+	if oai.OAIparralelRequests <= 0 {
+		ces.add(f("XX_OAI.OAIparralelRequests"), oai.OAIparralelRequests, "must be greater than 0")
+	}
+
+	// Collectors - only checked when they have been set
+	if c.SharePoint.SPsiteURL != "" {
+		checkURL(f("SharePoint.SPsiteURL"), c.SharePoint.SPsiteURL, ces)
+		checkFile(f("SharePoint.SPAuthFile"), c.SharePoint.SPAuthFile, ces)
+		if err := sharepoint.AuthStrategy(c.SharePoint.SPauthStrategy); err != nil {
+			ces.add(f("SharePoint.SPauthStrategy"), c.SharePoint.SPauthStrategy, "must be one of "+strings.Join(sharepoint.AuthStrategies, ", "))
+		}
+	}
+	if c.Website.URL != "" {
+		checkURL(f("Website.URL"), c.Website.URL, ces)
+	}
+	if c.Filesystem.RootDir != "" {
+		checkDir(f("Filesystem.RootDir"), c.Filesystem.RootDir, ces)
+	}
+}
+
+// checkWordCounts - the paragraph word counts have to make a sensible range
+func checkWordCounts(minField string, min int, maxField string, max int, ces *ConfigErrors) {
+	if max <= 0 {
+		ces.add(maxField, max, "must be greater than 0")
+	}
+	if min < 0 {
+		ces.add(minField, min, "must be 0 or greater")
+	}
+	if min >= max {
+		ces.add(minField, min, fmt.Sprintf("must be less than %v (%v)", maxField, max))
+	}
+}
+
+// checkURL - URL is required and has to be an absolute http(s) URL
+func checkURL(field string, value string, ces *ConfigErrors) {
+	if value == "" {
+		ces.add(field, value, "is required")
+		return
+	}
+
+	u, err := url.Parse(value)
+	if err != nil {
+		ces.add(field, value, fmt.Sprintf("is not a valid URL: %v", err))
+		return
+	}
+
+	if (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		ces.add(field, value, "must be an absolute http or https URL")
+	}
+}
+
+// checkFile - File is required and has to exist
+func checkFile(field string, value string, ces *ConfigErrors) {
+	if value == "" {
+		ces.add(field, value, "is required")
+		return
+	}
+
+	fi, err := os.Stat(value)
+	if err != nil {
+		ces.add(field, value, fmt.Sprintf("file can't be read: %v", err))
+		return
+	}
+
+	if fi.IsDir() {
+		ces.add(field, value, "is a directory not a file")
+	}
+}
+
+// checkDir - Directory has to exist
+func checkDir(field string, value string, ces *ConfigErrors) {
+	fi, err := os.Stat(value)
+	if err != nil {
+		ces.add(field, value, fmt.Sprintf("directory can't be read: %v", err))
+		return
+	}
+
+	if !fi.IsDir() {
+		ces.add(field, value, "is not a directory")
+	}
+}
@@ -1,5 +1,7 @@
 package models
 
+import "time"
+
 // Erato Interfaces
 
 // Create the Collector interface
@@ -12,6 +14,19 @@ type Collector interface {
 	// DownloadContentData(ContentRef) (*[]byte, error)
 }
 
+// LinkGraphExporter - Optional for Collectors that know how their content links together
+// e.g. the pages of a website
+type LinkGraphExporter interface {
+	ExportLinkGraph(dir string, name string) error
+}
+
+// CatalogCommitter - Optional for Collectors that keep state between runs e.g. a crawl to resume
+// The state is committed once the catalog has been analysed and stored, with the ContentRefs
+// whose analysis wasn't stored, so a run interrupted before then is done again
+type CatalogCommitter interface {
+	CommitCatalog(unstored []interface{}) error
+}
+
 // ContentRef - Interface for the Data that is sourced
 type ContentRef interface {
 	GetUniqueID() string
@@ -22,6 +37,52 @@ type ContentRef interface {
 	GetPath() string
 	GetPathHash() string
 	GetParentLocation() string
+	GetTimeLastModified() time.Time
+	GetSize() int64
+}
+
+// ContentMIMETyper - Optional for ContentRefs that know the MIME type of the content
+// e.g. from the HTTP Content-Type header
+type ContentMIMETyper interface {
+	GetMIMEType() string
+}
+
+// ContentPeeker - Optional for ContentRefs that already hold the content
+// so the start of it can be checked before it is downloaded
+type ContentPeeker interface {
+	PeekContent(n int) []byte
+}
+
+// ContentChangeTracker - Optional for ContentRefs from collectors that remember the last run
+// so content that hasn't changed since can be skipped rather than analysed again
+type ContentChangeTracker interface {
+	IsUnchanged() bool
+}
+
+// ContentMetaDataProvider - Optional for ContentRefs with metadata kept by the source
+// e.g. the list item columns of a SharePoint file
+type ContentMetaDataProvider interface {
+	GetMetaData() map[string]string
+}
+
+// ContentPermission - A user or group that can read the content
+type ContentPermission struct {
+	Principal     string
+	LoginName     string
+	PrincipalType string
+	Roles         []string
+}
+
+// ContentPermissionsProvider - Optional for ContentRefs from sources that control who can read them
+// so the catalogue can be security trimmed
+type ContentPermissionsProvider interface {
+	GetPermissions() []ContentPermission
+}
+
+// ContentDeletionTracker - Optional for ContentRefs from collectors that sync changes
+// so content deleted from the source since the last run can be tombstoned
+type ContentDeletionTracker interface {
+	IsDeleted() bool
 }
 
 type ContentPreparer interface {
@@ -29,6 +90,37 @@ type ContentPreparer interface {
 	Prepare(docData *[]byte) ([]string, error)
 }
 
+// ContentChunkPreparer - Optional extension to the ContentPreparer
+// for content where each text chunk can be traced back to its place in the source
+type ContentChunkPreparer interface {
+	PrepareChunks(docData *[]byte) ([]string, []ChunkMetaData, error)
+}
+
+// ChunkMetaData - Where a text chunk came from in the source content
+type ChunkMetaData struct {
+	Page      int    `json:",omitempty"`
+	Slide     int    `json:",omitempty"`
+	Sheet     string `json:",omitempty"`
+	CellRange string `json:",omitempty"`
+	Section   string `json:",omitempty"`
+}
+
+// ContentPropertiesReader - Optional for preparers that can read the document properties
+type ContentPropertiesReader interface {
+	ContentProperties(docData *[]byte) (ContentProperties, error)
+}
+
+// ContentProperties - Properties stored in the document itself
+type ContentProperties struct {
+	Title          string `json:",omitempty"`
+	Subject        string `json:",omitempty"`
+	Author         string `json:",omitempty"`
+	Keywords       string `json:",omitempty"`
+	LastModifiedBy string `json:",omitempty"`
+	Created        time.Time
+	Modified       time.Time
+}
+
 type ContentAnalyser interface {
 	NewContentAnalysis(EratoID string, content []string) ContentAnalysis
 	AnalyserDisabled() bool
@@ -43,3 +135,15 @@ type ContentAnalysis interface {
 	AnalysisResultError(i int) error
 	AnalysisResultData(i int) interface{}
 }
+
+// ContentMetaDataSetter - Optional for ContentAnalysis that can use the source metadata of the content
+// e.g. in the prompt
+type ContentMetaDataSetter interface {
+	SetMetaData(metaData map[string]string)
+}
+
+// ContentChunkMetaDataSetter - Optional for ContentAnalysis that record where each text chunk came from
+// on its result e.g. the page, slide or sheet, indexed by ParagraphNum-1
+type ContentChunkMetaDataSetter interface {
+	SetChunkMetaData(chunkMetaData []ChunkMetaData)
+}
@@ -0,0 +1,270 @@
+package content
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/pkoukk/tiktoken-go"
+	tiktoken_loader "github.com/pkoukk/tiktoken-go-loader"
+)
+
+// Chunkers that can be set in the Config
+const (
+	// Split on a count of words
+	ChunkerWords = "words"
+	// Split on sentence and paragraph boundaries with a count of tokens from the Model's tokenizer
+	ChunkerTokens = "tokens"
+)
+
+// The encoding used when the Model isn't set, e.g. for analysers that aren't OpenAI
+const defaultEncoding = "cl100k_base"
+
+// chunker - Measures text and splits it into chunks under the limit
+type chunker interface {
+	// size of the text in words or tokens
+	size(text string) int
+	limit() int
+	// split text that is bigger than the limit
+	split(text string) []string
+	// overlap - the end of a chunk to repeat at the start of the next one
+	overlap(text string) string
+	// withLimit - the same chunker with a different limit e.g. to leave room for a heading
+	withLimit(limit int) chunker
+}
+
+// chunker - The chunker set in the Config, words when not set
+func (c Config) chunker() (chunker, error) {
+	switch c.Chunker {
+	case "", ChunkerWords:
+		return wordChunker{maxWords: c.ParagraphMaxWordCount}, nil
+	case ChunkerTokens:
+		if c.ChunkMaxTokenCount <= 0 {
+			return nil, fmt.Errorf("chunker - ChunkMaxTokenCount must be greater than 0:%v", c.ChunkMaxTokenCount)
+		}
+		enc, err := tokenizer(c.Model)
+		if err != nil {
+			return nil, err
+		}
+		return tokenChunker{enc: enc, maxTokens: c.ChunkMaxTokenCount, overlapTokens: c.ChunkOverlapTokenCount}, nil
+	default:
+		return nil, fmt.Errorf("chunker - unknown Chunker:%v", c.Chunker)
+	}
+}
+
+// CheckChunker - Check the chunker can be created e.g. there is a tokenizer for the Model
+func (c Config) CheckChunker() error {
+	_, err := c.chunker()
+	return err
+}
+
+// wordChunker - The original chunking on a count of words
+type wordChunker struct {
+	maxWords int
+}
+
+func (wc wordChunker) size(text string) int {
+	return len(strings.Fields(text))
+}
+
+func (wc wordChunker) limit() int {
+	return wc.maxWords
+}
+
+func (wc wordChunker) split(text string) []string {
+	return chunkyVator(wc.maxWords, strings.Fields(text))
+}
+
+func (wc wordChunker) overlap(text string) string {
+	return ""
+}
+
+func (wc wordChunker) withLimit(limit int) chunker {
+	wc.maxWords = limit
+	return wc
+}
+
+var tokenizers = make(map[string]*tiktoken.Tiktoken)
+var tokenizersMu sync.Mutex
+
+func init() {
+	// Use the BPE ranks built into the binary rather than downloading them
+	tiktoken.SetBpeLoader(tiktoken_loader.NewOfflineLoader())
+}
+
+// tokenizer - The BPE tokenizer for the model, created once as loading the ranks is slow
+func tokenizer(model string) (*tiktoken.Tiktoken, error) {
+	tokenizersMu.Lock()
+	defer tokenizersMu.Unlock()
+
+	if enc, ok := tokenizers[model]; ok {
+		return enc, nil
+	}
+
+	var enc *tiktoken.Tiktoken
+	var err error
+	if model == "" {
+		enc, err = tiktoken.GetEncoding(defaultEncoding)
+	} else {
+		enc, err = tiktoken.EncodingForModel(model)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("tokenizer - Model:%v - %v", model, err)
+	}
+
+	tokenizers[model] = enc
+	return enc, nil
+}
+
+// tokenChunker - Chunks of up to maxTokens made of whole paragraphs and sentences
+// Each chunk starts with the last overlapTokens worth of sentences from the one before
+type tokenChunker struct {
+	enc           *tiktoken.Tiktoken
+	maxTokens     int
+	overlapTokens int
+}
+
+// textUnit - A sentence and the separator that goes before it
+type textUnit struct {
+	sep    string
+	text   string
+	tokens int
+}
+
+func (tc tokenChunker) size(text string) int {
+	return len(tc.enc.EncodeOrdinary(text))
+}
+
+func (tc tokenChunker) limit() int {
+	return tc.maxTokens
+}
+
+func (tc tokenChunker) split(text string) []string {
+	var chunks []string
+	var chunk []textUnit
+	tokens := 0
+	// Only emit a chunk when it has more than the overlap from the one before
+	added := false
+
+	for _, u := range tc.units(text) {
+		if added && tokens+u.tokens > tc.maxTokens {
+			chunks = append(chunks, strings.TrimSpace(joinUnits(chunk)))
+
+			// Start the next chunk with the end of this one leaving room for the sentence
+			chunk = tc.overlapUnits(chunk)
+			tokens = 0
+			for _, o := range chunk {
+				tokens += o.tokens
+			}
+			for len(chunk) > 0 && tokens+u.tokens > tc.maxTokens {
+				tokens -= chunk[0].tokens
+				chunk = chunk[1:]
+			}
+		}
+
+		chunk = append(chunk, u)
+		tokens += u.tokens
+		added = true
+	}
+
+	if added {
+		chunks = append(chunks, strings.TrimSpace(joinUnits(chunk)))
+	}
+
+	return chunks
+}
+
+func (tc tokenChunker) withLimit(limit int) chunker {
+	tc.maxTokens = limit
+	if tc.overlapTokens >= limit {
+		tc.overlapTokens = limit / 2
+	}
+	return tc
+}
+
+func (tc tokenChunker) overlap(text string) string {
+	return joinUnits(tc.overlapUnits(tc.units(text)))
+}
+
+// overlapUnits - The last sentences that fit in the overlap
+func (tc tokenChunker) overlapUnits(units []textUnit) []textUnit {
+	tokens := 0
+	i := len(units)
+	for i > 0 && tokens+units[i-1].tokens <= tc.overlapTokens {
+		i--
+		tokens += units[i].tokens
+	}
+
+	return append([]textUnit(nil), units[i:]...)
+}
+
+// units - Split the text into sentences keeping the paragraph and line breaks between them
+// Sentences that are too big on their own are cut on the token count
+func (tc tokenChunker) units(text string) []textUnit {
+	var units []textUnit
+
+	for p, para := range paragraphs(strings.Split(text, "\n")) {
+		for l, line := range strings.Split(para, "\n") {
+			for s, sentence := range sentences(line) {
+				sep := " "
+				if s == 0 {
+					sep = "\n"
+					if l == 0 {
+						sep = "\n\n"
+					}
+				}
+				if p == 0 && l == 0 && s == 0 {
+					sep = ""
+				}
+
+				toks := tc.enc.EncodeOrdinary(sentence)
+				for len(toks) > tc.maxTokens {
+					units = append(units, textUnit{sep: sep, text: tc.enc.Decode(toks[:tc.maxTokens]), tokens: tc.maxTokens})
+					toks = toks[tc.maxTokens:]
+					sep = ""
+				}
+				units = append(units, textUnit{sep: sep, text: tc.enc.Decode(toks), tokens: len(toks)})
+			}
+		}
+	}
+
+	return units
+}
+
+// joinUnits - Put the sentences back together with their separators
+// The separator of the first is dropped as it may be the overlap from another chunk
+func joinUnits(units []textUnit) string {
+	var sb strings.Builder
+	for i, u := range units {
+		if i > 0 {
+			sb.WriteString(u.sep)
+		}
+		sb.WriteString(u.text)
+	}
+	return sb.String()
+}
+
+// sentences - Split a line after each . ! or ? that is followed by a space
+func sentences(line string) []string {
+	var sentences []string
+
+	runes := []rune(strings.TrimSpace(line))
+	start := 0
+	for i := 0; i < len(runes)-1; i++ {
+		switch runes[i] {
+		case '.', '!', '?':
+			if runes[i+1] == ' ' || runes[i+1] == '\t' {
+				if s := strings.TrimSpace(string(runes[start : i+1])); s != "" {
+					sentences = append(sentences, s)
+				}
+				start = i + 1
+			}
+		}
+	}
+
+	if s := strings.TrimSpace(string(runes[start:])); s != "" {
+		sentences = append(sentences, s)
+	}
+
+	return sentences
+}
@@ -0,0 +1,245 @@
+package content
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestSentences(t *testing.T) {
+	tests := []struct {
+		name string
+		line string
+		want []string
+	}{
+		{"empty", "", nil},
+		{"blank", "   ", nil},
+		{"one sentence", "Hello world", []string{"Hello world"}},
+		{"full stop at the end", "Hello world.", []string{"Hello world."}},
+		{"split on . ! and ?", "One. Two! Three? Four", []string{"One.", "Two!", "Three?", "Four"}},
+		{"split on a tab", "One.\tTwo.", []string{"One.", "Two."}},
+		{"no space after the stop", "Version 1.2 is out. Get it", []string{"Version 1.2 is out.", "Get it"}},
+		{"trimmed", "  One.   Two.  ", []string{"One.", "Two."}},
+		{"runes", "Café. Ünïcode!", []string{"Café.", "Ünïcode!"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := sentences(tt.line)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("sentences(%q) = %q, want %q", tt.line, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestConfigChunker(t *testing.T) {
+	tests := []struct {
+		name    string
+		c       Config
+		want    interface{}
+		wantErr bool
+	}{
+		{"default words", Config{ParagraphMaxWordCount: 10}, wordChunker{}, false},
+		{"words", Config{Chunker: ChunkerWords, ParagraphMaxWordCount: 10}, wordChunker{}, false},
+		{"tokens", Config{Chunker: ChunkerTokens, ChunkMaxTokenCount: 10}, tokenChunker{}, false},
+		{"tokens for a model", Config{Chunker: ChunkerTokens, Model: "gpt-4", ChunkMaxTokenCount: 10}, tokenChunker{}, false},
+		{"tokens without a max", Config{Chunker: ChunkerTokens}, nil, true},
+		{"tokens for an unknown model", Config{Chunker: ChunkerTokens, Model: "not-a-model", ChunkMaxTokenCount: 10}, nil, true},
+		{"unknown chunker", Config{Chunker: "lines"}, nil, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ck, err := tt.c.chunker()
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("chunker() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if reflect.TypeOf(ck) != reflect.TypeOf(tt.want) {
+				t.Errorf("chunker() = %T, want %T", ck, tt.want)
+			}
+		})
+	}
+}
+
+func TestWordChunker(t *testing.T) {
+	tests := []struct {
+		name     string
+		maxWords int
+		text     string
+		size     int
+		want     []string
+	}{
+		{"under the limit", 5, "one two three", 3, []string{"one two three"}},
+		{"exactly the limit", 3, "one two three", 3, []string{"one two three"}},
+		{"over the limit", 2, "one two three four five", 5, []string{"one two", "three four", "five"}},
+		{"whitespace collapsed", 2, "one\ntwo\t\tthree", 3, []string{"one two", "three"}},
+		{"empty", 2, "", 0, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			wc := wordChunker{maxWords: tt.maxWords}
+			if got := wc.size(tt.text); got != tt.size {
+				t.Errorf("size() = %v, want %v", got, tt.size)
+			}
+			if got := wc.split(tt.text); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("split() = %q, want %q", got, tt.want)
+			}
+			if got := wc.overlap(tt.text); got != "" {
+				t.Errorf("overlap() = %q, want no overlap", got)
+			}
+		})
+	}
+
+	if got := (wordChunker{maxWords: 10}).withLimit(4).limit(); got != 4 {
+		t.Errorf("withLimit(4).limit() = %v, want 4", got)
+	}
+}
+
+func newTestTokenChunker(t *testing.T, maxTokens, overlapTokens int) tokenChunker {
+	t.Helper()
+	enc, err := tokenizer("")
+	if err != nil {
+		t.Fatalf("tokenizer() error = %v", err)
+	}
+	return tokenChunker{enc: enc, maxTokens: maxTokens, overlapTokens: overlapTokens}
+}
+
+func TestTokenChunkerWithLimit(t *testing.T) {
+	tests := []struct {
+		name        string
+		overlap     int
+		limit       int
+		wantOverlap int
+	}{
+		{"overlap kept", 4, 10, 4},
+		{"overlap equal to the limit halved", 10, 10, 5},
+		{"overlap over the limit halved", 20, 10, 5},
+		{"no overlap", 0, 10, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tc := newTestTokenChunker(t, 100, tt.overlap)
+			got := tc.withLimit(tt.limit).(tokenChunker)
+			if got.maxTokens != tt.limit {
+				t.Errorf("maxTokens = %v, want %v", got.maxTokens, tt.limit)
+			}
+			if got.overlapTokens != tt.wantOverlap {
+				t.Errorf("overlapTokens = %v, want %v", got.overlapTokens, tt.wantOverlap)
+			}
+		})
+	}
+}
+
+func TestTokenChunkerSplit(t *testing.T) {
+	tc := newTestTokenChunker(t, 1, 0)
+	sentenceTokens := tc.size("Alpha beta gamma.")
+
+	tests := []struct {
+		name      string
+		maxTokens int
+		overlap   int
+		text      string
+		want      []string
+	}{
+		{
+			name:      "fits in one chunk",
+			maxTokens: 100,
+			text:      "Alpha beta gamma. Alpha beta gamma.",
+			want:      []string{"Alpha beta gamma. Alpha beta gamma."},
+		},
+		{
+			name:      "split on sentences",
+			maxTokens: sentenceTokens * 2,
+			text:      "Alpha beta gamma. Alpha beta gamma. Alpha beta gamma.",
+			want:      []string{"Alpha beta gamma. Alpha beta gamma.", "Alpha beta gamma."},
+		},
+		{
+			name:      "paragraph breaks kept",
+			maxTokens: 100,
+			text:      "Alpha beta gamma.\n\nAlpha beta gamma.\nAlpha beta gamma.",
+			want:      []string{"Alpha beta gamma.\n\nAlpha beta gamma.\nAlpha beta gamma."},
+		},
+		{
+			name:      "overlap repeats the last sentence",
+			maxTokens: sentenceTokens * 2,
+			overlap:   sentenceTokens,
+			text:      "One two three. Four five six. Seven eight nine.",
+			want:      []string{"One two three. Four five six.", "Four five six. Seven eight nine."},
+		},
+		{
+			name: "empty",
+			text: "",
+			want: nil,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tc := newTestTokenChunker(t, tt.maxTokens, tt.overlap)
+			got := tc.split(tt.text)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("split(%q) = %q, want %q", tt.text, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTokenChunkerSplitLongSentence(t *testing.T) {
+	tests := []struct {
+		name      string
+		maxTokens int
+		overlap   int
+	}{
+		{"no overlap", 5, 0},
+		{"with overlap", 5, 2},
+	}
+
+	text := strings.Repeat("word ", 40)
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tc := newTestTokenChunker(t, tt.maxTokens, tt.overlap)
+			chunks := tc.split(text)
+			if len(chunks) < 2 {
+				t.Fatalf("split() = %q, want the sentence cut into several chunks", chunks)
+			}
+			for _, chunk := range chunks {
+				if size := tc.size(chunk); size > tt.maxTokens {
+					t.Errorf("chunk %q has %v tokens, over the limit of %v", chunk, size, tt.maxTokens)
+				}
+			}
+		})
+	}
+}
+
+func TestTokenChunkerOverlap(t *testing.T) {
+	tc := newTestTokenChunker(t, 1, 0)
+	sentenceTokens := tc.size("Four five six.")
+
+	tests := []struct {
+		name    string
+		overlap int
+		text    string
+		want    string
+	}{
+		{"no overlap", 0, "One two three. Four five six.", ""},
+		{"last sentence", sentenceTokens, "One two three. Four five six.", "Four five six."},
+		{"whole text", 100, "One two three. Four five six.", "One two three. Four five six."},
+		{"sentence bigger than the overlap", sentenceTokens - 1, "One two three. Four five six.", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tc := newTestTokenChunker(t, 100, tt.overlap)
+			if got := tc.overlap(tt.text); got != tt.want {
+				t.Errorf("overlap(%q) = %q, want %q", tt.text, got, tt.want)
+			}
+		})
+	}
+}
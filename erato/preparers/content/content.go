@@ -1,7 +1,11 @@
 package content
 
 import (
+	"Erato/erato/models"
 	"Erato/erato/preparers/docx"
+	"Erato/erato/preparers/ooxml"
+	"Erato/erato/preparers/pptx"
+	"Erato/erato/preparers/xlsx"
 	"bytes"
 	"fmt"
 	"strings"
@@ -15,94 +19,194 @@ import (
 type Config struct {
 	ParagraphMaxWordCount int
 	ParagraphMinWordCount int
-	Debug                 bool
+	// Chunker - ChunkerWords (the default) or ChunkerTokens
+	Chunker string
+	// Model - The tokenizer for ChunkerTokens is the one the Model uses
+	Model                  string
+	ChunkMaxTokenCount     int
+	ChunkOverlapTokenCount int
+	Debug                  bool
+}
+
+// PrepareWarning - The document can't be prepared, e.g. it is encrypted or malformed
+// Reported as a warning on the document rather than stopping the processing
+type PrepareWarning struct {
+	FileType string
+	Err      error
+}
+
+func (w *PrepareWarning) Error() string {
+	return fmt.Sprintf("%v document can't be prepared: %v", w.FileType, w.Err)
+}
+
+func (w *PrepareWarning) Unwrap() error {
+	return w.Err
 }
 
 // Type of Content that the Prepare function will convert to text
 type DOCX struct{ Config }
 type HTML struct{ Config }
 type PDF struct{ Config }
+type PPTX struct{ Config }
+type XLSX struct{ Config }
 
 // TODO - .pdf file extension will convert from pdf to text
 // TODO - .html file extension will convert from html to text
 
 // Prepare - function that converts a Word document file to text of string format
 func (dt DOCX) Prepare(docData *[]byte) ([]string, error) {
+	chunks, _, err := dt.PrepareChunks(docData)
+	return chunks, err
+}
+
+// PrepareChunks - Converts the Word document to Markdown keeping the headings, tables and lists
+// Chunks start at each heading and keep the heading as their Section
+func (dt DOCX) PrepareChunks(docData *[]byte) ([]string, []models.ChunkMetaData, error) {
 
 	c := dt.Config
 	var err error
-	var chunks []string
 
 	// fp := filepath.Clean(doc.FileName)
 	r, err := docx.NewReader(docData)
 	if err != nil {
-		return chunks, fmt.Errorf("convertWordToText-%v", err)
+		return nil, nil, fmt.Errorf("convertWordToText-%v", err)
 	}
 	defer r.Close()
 
-	// Read all the paragraphs
-	// Need to change this to a reader of byte slices
-
-	paraGraphs, err := r.ReadAll()
+	blocks, err := r.ReadBlocks()
 	if err != nil {
-		return chunks, fmt.Errorf("convertWordToText-%v", err)
+		return nil, nil, fmt.Errorf("convertWordToText-%v", err)
 	}
 
-	// Read all the paragraphs
-	for _, paraGraph := range paraGraphs {
+	cb, err := newChunkBuilder(c)
+	if err != nil {
+		return nil, nil, err
+	}
+	part := docx.PartBody
+
+	for _, b := range blocks {
+
+		// The supporting parts each get their own section after the body
+		if b.Part != part {
+			part = b.Part
+			title := map[string]string{
+				docx.PartFootnote: "Footnotes",
+				docx.PartComment:  "Comments",
+				docx.PartHeader:   "Headers and Footers",
+				docx.PartFooter:   "Headers and Footers",
+			}[part]
+
+			if title != cb.meta.Section {
+				cb.section(models.ChunkMetaData{Section: title})
+				cb.add(markdownHeading(1, title))
+			}
+		}
 
-		// Process the paragraph
-		pts := strings.Fields(paraGraph)
-		// less than 5 words in a paragraph then ignore
-		if len(pts) <= c.ParagraphMinWordCount {
-			continue
+		switch b.Type {
+		case docx.BlockHeading:
+			cb.section(models.ChunkMetaData{Section: b.Text})
+			cb.add(markdownHeading(b.Level, b.Text))
+
+		case docx.BlockTable:
+			cb.addTable(b.Rows)
+
+		case docx.BlockListItem:
+			marker := "-"
+			if b.Ordered {
+				marker = "1."
+			}
+			cb.add(strings.Repeat("  ", b.Level) + marker + " " + b.Text)
+
+		default:
+			switch b.Part {
+			case docx.PartFootnote:
+				cb.add("[^" + b.Ref + "]: " + b.Text)
+			case docx.PartComment:
+				cb.add("- **" + b.Ref + "**: " + b.Text)
+			default:
+				cb.add(b.Text)
+			}
 		}
+	}
 
-		// chunk further if bigger than the chunk size
-		if len(pts) > c.ParagraphMaxWordCount {
-			// If it is then create smaller chunks of text to then add onto the chunks []string
-			chunks = append(chunks, chunkyVator(c.ParagraphMaxWordCount, pts)...)
+	chunks, chunksMetaData := cb.result()
 
-		} else {
-			chunks = append(chunks, strings.Join(pts, " "))
-		}
+	// Return the chunked text in a string slice
+	return chunks, chunksMetaData, err
+}
+
+// ContentProperties - The core properties of the Word document
+func (dt DOCX) ContentProperties(docData *[]byte) (models.ContentProperties, error) {
+	r, err := docx.NewReader(docData)
+	if err != nil {
+		return models.ContentProperties{}, err
+	}
+	defer r.Close()
 
+	cp, err := r.CoreProperties()
+	if err != nil {
+		return models.ContentProperties{}, err
 	}
 
-	// Return the chunked text in a string slice
-	return chunks, err
+	return coreProperties(cp), nil
+}
+
+// coreProperties - Map the OOXML core properties to the models
+func coreProperties(cp ooxml.CoreProperties) models.ContentProperties {
+	return models.ContentProperties{
+		Title:          cp.Title,
+		Subject:        cp.Subject,
+		Author:         cp.Creator,
+		Keywords:       cp.Keywords,
+		LastModifiedBy: cp.LastModifiedBy,
+		Created:        cp.Created,
+		Modified:       cp.Modified,
+	}
 }
 
 // Prepare - Takes HTML date and converts to text using the openAPI service
 func (dt HTML) Prepare(docData *[]byte) ([]string, error) {
-	var err error
-	c := dt.Config
-	// debug := c.Debug
+	chunks, _, err := dt.PrepareChunks(docData)
+	return chunks, err
+}
+
+// PrepareChunks - Converts the HTML to Markdown and splits it into sections on the <h1> to <h6> headings
+// Each chunk keeps the breadcrumb of headings it sits under and sections are only
+// split further when they are bigger than the chunk size
+func (dt HTML) PrepareChunks(docData *[]byte) ([]string, []models.ChunkMetaData, error) {
+	if docData == nil {
+		return nil, nil, fmt.Errorf("convertHTMLToText-no data")
+	}
 
 	converter := md.NewConverter("", true, nil)
 
 	markdown, err := converter.ConvertString(string(*docData))
+	if err != nil {
+		return nil, nil, fmt.Errorf("convertHTMLToText-%v", err)
+	}
 
-	// if debug {
-	// 	fmt.Println("--------------------------------------------------------------------")
-	// 	fmt.Println(markdown)
-	// 	fmt.Println("--------------------------------------------------------------------")
-	// }
-
-	// Split into a slice of words
-	pts := strings.Fields(markdown)
-
-	chunks := chunkyVator(c.ParagraphMaxWordCount, pts)
+	return prepareMarkdown(dt.Config, strings.Split(markdown, "\n"))
+}
 
+// Prepare - Takes PDF data and converts to text chunks
+func (dt PDF) Prepare(docData *[]byte) ([]string, error) {
+	chunks, _, err := dt.PrepareChunks(docData)
 	return chunks, err
-
 }
 
-// Prepare - Takes HTML date and converts to text using the openAPI service
-func (dt PDF) Prepare(docData *[]byte) ([]string, error) {
-	var err error
+// PrepareChunks - Converts the PDF a page at a time so each chunk keeps its page number
+// Encrypted and malformed files are returned as a PrepareWarning
+func (dt PDF) PrepareChunks(docData *[]byte) (chunks []string, chunksMetaData []models.ChunkMetaData, err error) {
 	c := dt.Config
-	var buf bytes.Buffer
+
+	// The pdf package panics on malformed files rather than returning an error
+	defer func() {
+		if r := recover(); r != nil {
+			chunks = nil
+			chunksMetaData = nil
+			err = &PrepareWarning{FileType: ".pdf", Err: fmt.Errorf("malformed PDF: %v", r)}
+		}
+	}()
 
 	//  create a new reader from the docData byte slice
 	reader := bytes.NewReader(*docData)
@@ -110,28 +214,214 @@ func (dt PDF) Prepare(docData *[]byte) ([]string, error) {
 	// convert size to and in64
 	size := int64(len(*docData))
 
-	// Create a new pdf reader
+	// Create a new pdf reader - this fails for encrypted files that need a password
 	pdfr, err := pdf.NewReader(reader, size)
 	if err != nil {
-		return nil, err
+		return nil, nil, &PrepareWarning{FileType: ".pdf", Err: err}
+	}
+
+	ck, err := c.chunker()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// cache fonts so the charmap isn't continually parsed
+	fonts := make(map[string]*pdf.Font)
+
+	for i := 1; i <= pdfr.NumPage(); i++ {
+		page := pdfr.Page(i)
+		if page.V.IsNull() {
+			continue
+		}
+
+		for _, name := range page.Fonts() {
+			if _, ok := fonts[name]; !ok {
+				f := page.Font(name)
+				fonts[name] = &f
+			}
+		}
+
+		text, err := page.GetPlainText(fonts)
+		if err != nil {
+			if c.Debug {
+				fmt.Printf("PDF.PrepareChunks - DEBUG - Unable to read page:%v - %v\n", i, err)
+			}
+			continue
+		}
+
+		// Split into a slice of words
+		pts := strings.Fields(text)
+		// less than the minimum words on a page then ignore
+		if len(pts) <= c.ParagraphMinWordCount {
+			continue
+		}
+
+		// chunk further if bigger than the chunk size
+		for _, chunk := range ck.split(text) {
+			chunks = append(chunks, chunk)
+			chunksMetaData = append(chunksMetaData, models.ChunkMetaData{Page: i})
+		}
+	}
+
+	// Return the chunked text in a string slice
+	return chunks, chunksMetaData, nil
+
+}
+
+// Prepare - Takes PowerPoint data and converts to text chunks
+func (dt PPTX) Prepare(docData *[]byte) ([]string, error) {
+	chunks, _, err := dt.PrepareChunks(docData)
+	return chunks, err
+}
+
+// PrepareChunks - One chunk per slide with the title, body and speaker notes
+// Each chunk is tagged with the slide number
+func (dt PPTX) PrepareChunks(docData *[]byte) ([]string, []models.ChunkMetaData, error) {
+	c := dt.Config
+
+	r, err := pptx.NewReader(docData)
+	if err != nil {
+		return nil, nil, fmt.Errorf("convertPowerPointToText-%v", err)
+	}
+
+	slides, err := r.ReadSlides()
+	if err != nil {
+		return nil, nil, fmt.Errorf("convertPowerPointToText-%v", err)
 	}
 
-	io, err := pdfr.GetPlainText()
+	cb, err := newChunkBuilder(c)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
+	}
+
+	for _, slide := range slides {
+		cb.section(models.ChunkMetaData{Slide: slide.Number, Section: slide.Title})
+
+		heading := fmt.Sprintf("Slide %v", slide.Number)
+		if slide.Title != "" {
+			heading += ": " + slide.Title
+		}
+		cb.add(markdownHeading(2, heading))
+
+		if len(slide.Body) > 0 {
+			cb.add(strings.Join(slide.Body, "\n"))
+		}
+
+		for _, table := range slide.Tables {
+			cb.addTable(table)
+		}
+
+		if len(slide.Notes) > 0 {
+			cb.add("**Speaker notes:** " + strings.Join(slide.Notes, "\n"))
+		}
 	}
 
-	// Read the io.Reader into the buffer and convert to a string
-	buf.ReadFrom(io)
-	content := buf.String()
+	chunks, chunksMetaData := cb.result()
 
-	// Split into a slice of words
-	pts := strings.Fields(content)
+	return chunks, chunksMetaData, nil
+}
+
+// ContentProperties - The core properties of the PowerPoint presentation
+func (dt PPTX) ContentProperties(docData *[]byte) (models.ContentProperties, error) {
+	r, err := pptx.NewReader(docData)
+	if err != nil {
+		return models.ContentProperties{}, err
+	}
 
-	chunks := chunkyVator(c.ParagraphMaxWordCount, pts)
+	cp, err := r.CoreProperties()
+	if err != nil {
+		return models.ContentProperties{}, err
+	}
+
+	return coreProperties(cp), nil
+}
 
+// Prepare - Takes Excel data and converts to text chunks
+func (dt XLSX) Prepare(docData *[]byte) ([]string, error) {
+	chunks, _, err := dt.PrepareChunks(docData)
 	return chunks, err
+}
+
+// PrepareChunks - Each table on each sheet as a Markdown table
+// Big tables are split into groups of rows with the header row repeated
+// Each chunk is tagged with the sheet name and the cell range of its rows
+func (dt XLSX) PrepareChunks(docData *[]byte) ([]string, []models.ChunkMetaData, error) {
+	c := dt.Config
+
+	r, err := xlsx.NewReader(docData)
+	if err != nil {
+		return nil, nil, fmt.Errorf("convertExcelToText-%v", err)
+	}
+
+	sheets, err := r.ReadSheets()
+	if err != nil {
+		return nil, nil, fmt.Errorf("convertExcelToText-%v", err)
+	}
+
+	cb, err := newChunkBuilder(c)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	for _, sheet := range sheets {
+		for _, table := range sheet.Tables {
+			header := table.Rows[0]
+			rows := table.Rows[1:]
+
+			var cells [][]string
+			for _, row := range rows {
+				cells = append(cells, row.Cells)
+			}
+
+			// Leave room for the heading in each chunk
+			heading := func(cellRange string) string {
+				return markdownHeading(2, fmt.Sprintf("Sheet: %v (%v)", sheet.Name, cellRange))
+			}
+			limit := cb.ck.limit() - cb.ck.size(heading(table.CellRange(header.Number, header.Number)))
+
+			groups := tableRowGroups(header.Cells, cells, limit, cb.ck.size)
+			if len(groups) == 0 {
+				// Only a header row
+				groups = [][][]string{nil}
+			}
+
+			first := 0
+			for _, group := range groups {
+				firstRow, lastRow := header.Number, header.Number
+				if len(group) > 0 {
+					lastRow = rows[first+len(group)-1].Number
+					if first > 0 {
+						firstRow = rows[first].Number
+					}
+				}
+				first += len(group)
+
+				cellRange := table.CellRange(firstRow, lastRow)
+				cb.section(models.ChunkMetaData{Sheet: sheet.Name, CellRange: cellRange})
+				md := heading(cellRange) + "\n\n" + markdownTable(header.Cells, group)
+				cb.addChunk(md)
+			}
+		}
+	}
+
+	chunks, chunksMetaData := cb.result()
+
+	return chunks, chunksMetaData, nil
+}
+
+// ContentProperties - The core properties of the Excel workbook
+func (dt XLSX) ContentProperties(docData *[]byte) (models.ContentProperties, error) {
+	r, err := xlsx.NewReader(docData)
+	if err != nil {
+		return models.ContentProperties{}, err
+	}
+
+	cp, err := r.CoreProperties()
+	if err != nil {
+		return models.ContentProperties{}, err
+	}
 
+	return coreProperties(cp), nil
 }
 
 // Return a []string of chunks of text - each chunk is of length c.ChunkWordCount
@@ -0,0 +1,222 @@
+package content
+
+import (
+	"Erato/erato/models"
+	"strings"
+)
+
+// chunkBuilder - Groups blocks of Markdown into chunks under the size limit of the chunker
+// A chunk never spans two sections and chunks of ParagraphMinWordCount words or less are dropped
+type chunkBuilder struct {
+	c  Config
+	ck chunker
+	// base - the chunker from the Config before any room is made for the context
+	base chunker
+	// context - text at the start of every chunk in the section e.g. the heading breadcrumb
+	context  string
+	blocks   []string
+	size     int
+	meta     models.ChunkMetaData
+	chunks   []string
+	metaData []models.ChunkMetaData
+}
+
+func newChunkBuilder(c Config) (*chunkBuilder, error) {
+	ck, err := c.chunker()
+	if err != nil {
+		return nil, err
+	}
+	return &chunkBuilder{c: c, ck: ck, base: ck}, nil
+}
+
+// section - Start a new section, any text held is flushed to a chunk
+func (cb *chunkBuilder) section(meta models.ChunkMetaData) {
+	cb.contextSection(meta, "")
+}
+
+// contextSection - Start a new section where every chunk starts with the context
+// The chunks' limit is reduced to make room for it
+func (cb *chunkBuilder) contextSection(meta models.ChunkMetaData, context string) {
+	cb.flush()
+	cb.meta = meta
+	cb.context = context
+	cb.ck = cb.base
+
+	if context != "" {
+		limit := cb.base.limit() - cb.base.size(context)
+		if limit < cb.base.limit()/2 {
+			limit = cb.base.limit() / 2
+		}
+		if limit < 1 {
+			limit = 1
+		}
+		cb.ck = cb.base.withLimit(limit)
+	}
+}
+
+// add - Add a block of Markdown to the current chunk
+func (cb *chunkBuilder) add(md string) {
+	if strings.TrimSpace(md) == "" {
+		return
+	}
+	size := cb.ck.size(md)
+
+	// Fits in the current chunk
+	if cb.size+size <= cb.ck.limit() {
+		cb.blocks = append(cb.blocks, md)
+		cb.size += size
+		return
+	}
+
+	// Too big to share a chunk - the next chunk starts with the overlap from this one
+	if size <= cb.ck.limit() {
+		chunk := cb.flush()
+		if overlap := cb.ck.overlap(chunk); overlap != "" && cb.ck.size(overlap)+size <= cb.ck.limit() {
+			cb.blocks = []string{overlap}
+			cb.size = cb.ck.size(overlap)
+		}
+		cb.blocks = append(cb.blocks, md)
+		cb.size += size
+		return
+	}
+
+	// Too big for any chunk so split it along with the text held e.g. its heading
+	// The last part is held so the blocks that follow can join it
+	parts := cb.ck.split(strings.Join(append(cb.blocks, md), "\n\n"))
+	cb.blocks = nil
+	cb.size = 0
+	for i, part := range parts {
+		if i == len(parts)-1 {
+			cb.blocks = []string{part}
+			cb.size = cb.ck.size(part)
+			break
+		}
+		cb.addChunk(part)
+	}
+}
+
+// addTable - Add a table, splitting by rows with the header repeated when it is too big
+func (cb *chunkBuilder) addTable(rows [][]string) {
+	if len(rows) == 0 {
+		return
+	}
+
+	md := markdownTable(rows[0], rows[1:])
+	if cb.ck.size(md) <= cb.ck.limit() {
+		cb.add(md)
+		return
+	}
+
+	cb.flush()
+	for _, group := range tableRowGroups(rows[0], rows[1:], cb.ck.limit(), cb.ck.size) {
+		cb.addChunk(markdownTable(rows[0], group))
+	}
+}
+
+// flush - Turn the blocks held into a chunk
+func (cb *chunkBuilder) flush() string {
+	if len(cb.blocks) == 0 {
+		return ""
+	}
+
+	chunk := strings.Join(cb.blocks, "\n\n")
+	cb.addChunk(chunk)
+	cb.blocks = nil
+	cb.size = 0
+
+	return chunk
+}
+
+func (cb *chunkBuilder) addChunk(chunk string) {
+	// less than the minimum number of words then ignore
+	if len(strings.Fields(chunk)) <= cb.c.ParagraphMinWordCount {
+		return
+	}
+
+	if cb.context != "" {
+		chunk = cb.context + "\n\n" + chunk
+	}
+
+	cb.chunks = append(cb.chunks, chunk)
+	cb.metaData = append(cb.metaData, cb.meta)
+}
+
+// result - The chunks and where they came from
+func (cb *chunkBuilder) result() ([]string, []models.ChunkMetaData) {
+	cb.flush()
+	return cb.chunks, cb.metaData
+}
+
+// tableRowGroups - Split the rows into groups that fit under the limit with the header
+func tableRowGroups(header []string, rows [][]string, limit int, size func(string) int) [][][]string {
+	var groups [][][]string
+	var group [][]string
+
+	headerSize := size(strings.Join(header, " "))
+	total := headerSize
+
+	for _, row := range rows {
+		rowSize := size(strings.Join(row, " "))
+		if len(group) > 0 && total+rowSize > limit {
+			groups = append(groups, group)
+			group = nil
+			total = headerSize
+		}
+		group = append(group, row)
+		total += rowSize
+	}
+
+	if len(group) > 0 {
+		groups = append(groups, group)
+	}
+
+	return groups
+}
+
+// markdownTable - Render the rows as a Markdown table
+func markdownTable(header []string, rows [][]string) string {
+	width := len(header)
+	for _, row := range rows {
+		if len(row) > width {
+			width = len(row)
+		}
+	}
+
+	var sb strings.Builder
+	writeRow := func(cells []string) {
+		sb.WriteString("|")
+		for i := 0; i < width; i++ {
+			cell := ""
+			if i < len(cells) {
+				cell = markdownCell(cells[i])
+			}
+			sb.WriteString(" " + cell + " |")
+		}
+		sb.WriteString("\n")
+	}
+
+	writeRow(header)
+	sb.WriteString("|" + strings.Repeat(" --- |", width) + "\n")
+	for _, row := range rows {
+		writeRow(row)
+	}
+
+	return strings.TrimSuffix(sb.String(), "\n")
+}
+
+// markdownCell - Keep the cell on one line and escape the column separator
+func markdownCell(s string) string {
+	s = strings.Join(strings.Fields(s), " ")
+	return strings.ReplaceAll(s, "|", `\|`)
+}
+
+// markdownHeading - # for each level
+func markdownHeading(level int, text string) string {
+	if level < 1 {
+		level = 1
+	}
+	if level > 6 {
+		level = 6
+	}
+	return strings.Repeat("#", level) + " " + text
+}
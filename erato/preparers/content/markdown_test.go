@@ -0,0 +1,319 @@
+package content
+
+import (
+	"Erato/erato/models"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestMarkdownTable(t *testing.T) {
+	tests := []struct {
+		name   string
+		header []string
+		rows   [][]string
+		want   string
+	}{
+		{
+			name:   "header only",
+			header: []string{"a", "b"},
+			want:   "| a | b |\n| --- | --- |",
+		},
+		{
+			name:   "rows",
+			header: []string{"a", "b"},
+			rows:   [][]string{{"1", "2"}, {"3", "4"}},
+			want:   "| a | b |\n| --- | --- |\n| 1 | 2 |\n| 3 | 4 |",
+		},
+		{
+			name:   "short rows padded",
+			header: []string{"a", "b"},
+			rows:   [][]string{{"1"}},
+			want:   "| a | b |\n| --- | --- |\n| 1 |  |",
+		},
+		{
+			name:   "long rows widen the table",
+			header: []string{"a"},
+			rows:   [][]string{{"1", "2"}},
+			want:   "| a |  |\n| --- | --- |\n| 1 | 2 |",
+		},
+		{
+			name:   "separator escaped",
+			header: []string{"a|b"},
+			rows:   [][]string{{"x | y"}},
+			want:   "| a\\|b |\n| --- |\n| x \\| y |",
+		},
+		{
+			name:   "cell kept on one line",
+			header: []string{"a"},
+			rows:   [][]string{{"one\ntwo\t three"}},
+			want:   "| a |\n| --- |\n| one two three |",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := markdownTable(tt.header, tt.rows); got != tt.want {
+				t.Errorf("markdownTable() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTableRowGroups(t *testing.T) {
+	words := wordChunker{}.size
+
+	tests := []struct {
+		name   string
+		header []string
+		rows   [][]string
+		limit  int
+		want   [][][]string
+	}{
+		{
+			name:   "no rows",
+			header: []string{"a", "b"},
+			limit:  10,
+			want:   nil,
+		},
+		{
+			name:   "all rows fit",
+			header: []string{"a", "b"},
+			rows:   [][]string{{"1", "2"}, {"3", "4"}},
+			limit:  10,
+			want:   [][][]string{{{"1", "2"}, {"3", "4"}}},
+		},
+		{
+			name:   "header counted in every group",
+			header: []string{"a", "b"},
+			rows:   [][]string{{"1", "2"}, {"3", "4"}, {"5", "6"}},
+			limit:  4,
+			want:   [][][]string{{{"1", "2"}}, {{"3", "4"}}, {{"5", "6"}}},
+		},
+		{
+			name:   "grouped under the limit",
+			header: []string{"a"},
+			rows:   [][]string{{"1"}, {"2"}, {"3"}, {"4"}, {"5"}},
+			limit:  3,
+			want:   [][][]string{{{"1"}, {"2"}}, {{"3"}, {"4"}}, {{"5"}}},
+		},
+		{
+			name:   "row bigger than the limit on its own",
+			header: []string{"a"},
+			rows:   [][]string{{"1"}, {"2 3 4 5"}, {"6"}},
+			limit:  3,
+			want:   [][][]string{{{"1"}}, {{"2 3 4 5"}}, {{"6"}}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := tableRowGroups(tt.header, tt.rows, tt.limit, words)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("tableRowGroups() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMarkdownHeading(t *testing.T) {
+	tests := []struct {
+		level int
+		want  string
+	}{
+		{0, "# Title"},
+		{1, "# Title"},
+		{3, "### Title"},
+		{6, "###### Title"},
+		{7, "###### Title"},
+	}
+
+	for _, tt := range tests {
+		if got := markdownHeading(tt.level, "Title"); got != tt.want {
+			t.Errorf("markdownHeading(%v) = %q, want %q", tt.level, got, tt.want)
+		}
+	}
+}
+
+// chunkBuilderStep - A section or block added to a chunkBuilder
+type chunkBuilderStep struct {
+	section *models.ChunkMetaData
+	context string
+	block   string
+	table   [][]string
+}
+
+func TestChunkBuilder(t *testing.T) {
+	intro := models.ChunkMetaData{Section: "Intro"}
+	more := models.ChunkMetaData{Section: "More"}
+
+	tests := []struct {
+		name     string
+		c        Config
+		steps    []chunkBuilderStep
+		want     []string
+		wantMeta []models.ChunkMetaData
+	}{
+		{
+			name:     "blocks share a chunk",
+			c:        Config{ParagraphMaxWordCount: 10},
+			steps:    []chunkBuilderStep{{block: "one two"}, {block: "three four"}},
+			want:     []string{"one two\n\nthree four"},
+			wantMeta: []models.ChunkMetaData{{}},
+		},
+		{
+			name:     "blank blocks ignored",
+			c:        Config{ParagraphMaxWordCount: 10},
+			steps:    []chunkBuilderStep{{block: "one two"}, {block: "  \n"}, {block: "three"}},
+			want:     []string{"one two\n\nthree"},
+			wantMeta: []models.ChunkMetaData{{}},
+		},
+		{
+			name:     "block that does not fit starts a chunk",
+			c:        Config{ParagraphMaxWordCount: 3},
+			steps:    []chunkBuilderStep{{block: "one two"}, {block: "three four"}},
+			want:     []string{"one two", "three four"},
+			wantMeta: []models.ChunkMetaData{{}, {}},
+		},
+		{
+			name:     "block larger than the limit split with the text held",
+			c:        Config{ParagraphMaxWordCount: 4},
+			steps:    []chunkBuilderStep{{block: "one"}, {block: "two three four five six"}, {block: "seven"}},
+			want:     []string{"one two three four", "five six\n\nseven"},
+			wantMeta: []models.ChunkMetaData{{}, {}},
+		},
+		{
+			name:     "chunks at or under the minimum dropped",
+			c:        Config{ParagraphMaxWordCount: 3, ParagraphMinWordCount: 1},
+			steps:    []chunkBuilderStep{{block: "one two"}, {block: "three four five"}, {block: "six"}},
+			want:     []string{"one two", "three four five"},
+			wantMeta: []models.ChunkMetaData{{}, {}},
+		},
+		{
+			name: "sections never share a chunk",
+			c:    Config{ParagraphMaxWordCount: 10},
+			steps: []chunkBuilderStep{
+				{section: &intro}, {block: "one two"},
+				{section: &more}, {block: "three four"},
+			},
+			want:     []string{"one two", "three four"},
+			wantMeta: []models.ChunkMetaData{intro, more},
+		},
+		{
+			name: "context starts every chunk",
+			c:    Config{ParagraphMaxWordCount: 6},
+			steps: []chunkBuilderStep{
+				{section: &intro, context: "A > B"},
+				{block: "one two three"}, {block: "four five"},
+			},
+			want:     []string{"A > B\n\none two three", "A > B\n\nfour five"},
+			wantMeta: []models.ChunkMetaData{intro, intro},
+		},
+		{
+			name: "context shrinks the limit to no less than half",
+			c:    Config{ParagraphMaxWordCount: 4},
+			steps: []chunkBuilderStep{
+				{section: &intro, context: "A > B > C > D"},
+				{block: "one two three"},
+			},
+			want:     []string{"A > B > C > D\n\none two", "A > B > C > D\n\nthree"},
+			wantMeta: []models.ChunkMetaData{intro, intro},
+		},
+		{
+			name: "limit restored without context",
+			c:    Config{ParagraphMaxWordCount: 4},
+			steps: []chunkBuilderStep{
+				{section: &intro, context: "A > B > C > D"},
+				{block: "one two"},
+				{section: &more},
+				{block: "three four five six"},
+			},
+			want:     []string{"A > B > C > D\n\none two", "three four five six"},
+			wantMeta: []models.ChunkMetaData{intro, more},
+		},
+		{
+			name:     "table that fits",
+			c:        Config{ParagraphMaxWordCount: 100},
+			steps:    []chunkBuilderStep{{table: [][]string{{"a", "b"}, {"1", "2"}}}},
+			want:     []string{"| a | b |\n| --- | --- |\n| 1 | 2 |"},
+			wantMeta: []models.ChunkMetaData{{}},
+		},
+		{
+			name: "table too big to share a chunk flushes the text held",
+			c:    Config{ParagraphMaxWordCount: 14},
+			steps: []chunkBuilderStep{
+				{block: "before"},
+				{table: [][]string{{"a", "b"}, {"1", "2"}, {"3", "4"}}},
+			},
+			want: []string{
+				"before",
+				"| a | b |\n| --- | --- |\n| 1 | 2 |\n| 3 | 4 |",
+			},
+			wantMeta: []models.ChunkMetaData{{}, {}},
+		},
+		{
+			name:     "header only table",
+			c:        Config{ParagraphMaxWordCount: 100},
+			steps:    []chunkBuilderStep{{table: [][]string{{"a", "b"}}}},
+			want:     []string{"| a | b |\n| --- | --- |"},
+			wantMeta: []models.ChunkMetaData{{}},
+		},
+		{
+			name:  "empty table",
+			c:     Config{ParagraphMaxWordCount: 100},
+			steps: []chunkBuilderStep{{table: [][]string{}}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cb, err := newChunkBuilder(tt.c)
+			if err != nil {
+				t.Fatalf("newChunkBuilder() error = %v", err)
+			}
+
+			for _, step := range tt.steps {
+				switch {
+				case step.section != nil:
+					cb.contextSection(*step.section, step.context)
+				case step.table != nil:
+					cb.addTable(step.table)
+				default:
+					cb.add(step.block)
+				}
+			}
+
+			chunks, meta := cb.result()
+			if !reflect.DeepEqual(chunks, tt.want) {
+				t.Errorf("chunks = %q, want %q", chunks, tt.want)
+			}
+			if !reflect.DeepEqual(meta, tt.wantMeta) {
+				t.Errorf("metaData = %+v, want %+v", meta, tt.wantMeta)
+			}
+		})
+	}
+}
+
+func TestChunkBuilderTableSplit(t *testing.T) {
+	c := Config{ParagraphMaxWordCount: 20}
+	cb, err := newChunkBuilder(c)
+	if err != nil {
+		t.Fatalf("newChunkBuilder() error = %v", err)
+	}
+
+	rows := [][]string{{"name", "value"}}
+	for i := 0; i < 10; i++ {
+		rows = append(rows, []string{"key", "val"})
+	}
+	cb.addTable(rows)
+
+	chunks, _ := cb.result()
+	if len(chunks) < 2 {
+		t.Fatalf("addTable() = %q, want the table split by rows", chunks)
+	}
+	for _, chunk := range chunks {
+		if !strings.HasPrefix(chunk, "| name | value |\n| --- | --- |\n") {
+			t.Errorf("chunk %q does not start with the header", chunk)
+		}
+	}
+}
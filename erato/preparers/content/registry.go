@@ -0,0 +1,214 @@
+package content
+
+import (
+	"Erato/erato/models"
+	"bytes"
+	"errors"
+	"mime"
+	"strings"
+)
+
+var ErrUnsupportedType = errors.New("unsupported content type")
+
+// Registration - How a type of content is recognised and the preparer created for it
+type Registration struct {
+	// File extension recorded on the Document e.g. ".docx"
+	FileExt    string
+	Extensions []string
+	MIMETypes  []string
+	// Magic bytes the content starts with, matched case insensitively after any white space
+	Signatures []string
+	// Name that must also be in the content e.g. the main part of a zip based Office file
+	Marker string
+	New    func(c Config) models.ContentPreparer
+}
+
+// Match scores - the content itself is trusted over what the server or file name says
+// A signature without its marker e.g. the PK zip header of an Office file whose main part
+// is past the start of the content that was checked only counts for as much as an extension
+// and only alongside the extension or MIME type, as any zip file e.g. a .odt or .jar has it
+const (
+	extensionScore     = 1
+	mimeTypeScore      = 2
+	signatureScore     = 4
+	signatureOnlyScore = 1
+)
+
+var registry []Registration
+
+func init() {
+	Register(Registration{
+		FileExt:    ".docx",
+		Extensions: []string{".docx"},
+		MIMETypes:  []string{"application/vnd.openxmlformats-officedocument.wordprocessingml.document"},
+		Signatures: []string{"PK\x03\x04"},
+		Marker:     "word/document.xml",
+		New:        func(c Config) models.ContentPreparer { return DOCX{Config: c} },
+	})
+	Register(Registration{
+		FileExt:    ".pptx",
+		Extensions: []string{".pptx"},
+		MIMETypes:  []string{"application/vnd.openxmlformats-officedocument.presentationml.presentation"},
+		Signatures: []string{"PK\x03\x04"},
+		Marker:     "ppt/presentation.xml",
+		New:        func(c Config) models.ContentPreparer { return PPTX{Config: c} },
+	})
+	Register(Registration{
+		FileExt:    ".xlsx",
+		Extensions: []string{".xlsx"},
+		MIMETypes:  []string{"application/vnd.openxmlformats-officedocument.spreadsheetml.sheet"},
+		Signatures: []string{"PK\x03\x04"},
+		Marker:     "xl/workbook.xml",
+		New:        func(c Config) models.ContentPreparer { return XLSX{Config: c} },
+	})
+	Register(Registration{
+		FileExt:    ".pdf",
+		Extensions: []string{".pdf"},
+		MIMETypes:  []string{"application/pdf", "application/x-pdf"},
+		Signatures: []string{"%PDF-"},
+		New:        func(c Config) models.ContentPreparer { return PDF{Config: c} },
+	})
+	Register(Registration{
+		FileExt:    ".html",
+		Extensions: []string{".html", ".htm", ".xhtml"},
+		MIMETypes:  []string{"text/html", "application/xhtml+xml"},
+		Signatures: []string{"<!doctype html", "<html"},
+		New:        func(c Config) models.ContentPreparer { return HTML{Config: c} },
+	})
+	Register(Registration{
+		FileExt:    ".txt",
+		Extensions: []string{".txt", ".text"},
+		MIMETypes:  []string{"text/plain"},
+		New:        func(c Config) models.ContentPreparer { return TXT{Config: c} },
+	})
+	Register(Registration{
+		FileExt:    ".md",
+		Extensions: []string{".md", ".markdown"},
+		MIMETypes:  []string{"text/markdown", "text/x-markdown"},
+		New:        func(c Config) models.ContentPreparer { return MD{Config: c} },
+	})
+	Register(Registration{
+		FileExt:    ".csv",
+		Extensions: []string{".csv"},
+		MIMETypes:  []string{"text/csv", "application/csv"},
+		New:        func(c Config) models.ContentPreparer { return CSV{Config: c} },
+	})
+	Register(Registration{
+		FileExt:    ".json",
+		Extensions: []string{".json"},
+		MIMETypes:  []string{"application/json", "text/json"},
+		New:        func(c Config) models.ContentPreparer { return JSON{Config: c} },
+	})
+}
+
+// Register - Add a preparer to the registry
+// Registration is not safe to run alongside Match so should be done before cataloging
+func Register(r Registration) {
+	registry = append(registry, r)
+}
+
+// Match - The registered preparer that best matches the file extension,
+// the MIME type e.g. from an HTTP Content-Type and the start of the content
+// Any of them can be empty. Returns ErrUnsupportedType when nothing matches
+func Match(ext string, mimeType string, data []byte) (Registration, error) {
+	ext = strings.ToLower(ext)
+	mimeType = mediaType(mimeType)
+
+	best := -1
+	bestScore := 0
+
+	for i, r := range registry {
+		score := 0
+		if ext != "" && contains(r.Extensions, ext) {
+			score += extensionScore
+		}
+		if mimeType != "" && contains(r.MIMETypes, mimeType) {
+			score += mimeTypeScore
+		}
+		if sig := r.signatureScore(data); sig == signatureScore || score > 0 {
+			score += sig
+		}
+
+		// The first registered wins a tie
+		if score > bestScore {
+			best = i
+			bestScore = score
+		}
+	}
+
+	if best == -1 {
+		return Registration{}, ErrUnsupportedType
+	}
+
+	return registry[best], nil
+}
+
+// DocumentExtensions - The file extensions of the registered preparers other than web pages e.g. ".pdf"
+func DocumentExtensions() []string {
+	var exts []string
+	for _, r := range registry {
+		if r.FileExt == ".html" {
+			continue
+		}
+		exts = append(exts, r.Extensions...)
+	}
+	return exts
+}
+
+// Sniff - The registered preparer whose signature and marker match the content
+// All of the content is needed to find the marker of a zip based Office file
+func Sniff(data []byte) (Registration, error) {
+	for _, r := range registry {
+		if r.signatureScore(data) == signatureScore {
+			return r, nil
+		}
+	}
+	return Registration{}, ErrUnsupportedType
+}
+
+// signatureScore - The signatureScore when the content starts with one of the signatures and has the marker,
+// the signatureOnlyScore when the marker isn't in the content, which may only be the start of it
+func (r Registration) signatureScore(data []byte) int {
+	if len(data) == 0 || len(r.Signatures) == 0 {
+		return 0
+	}
+
+	head := bytes.TrimLeft(bytes.TrimPrefix(data, utf8BOM), " \t\r\n")
+	if len(head) > 512 {
+		head = head[:512]
+	}
+
+	for _, sig := range r.Signatures {
+		if len(head) >= len(sig) && strings.EqualFold(string(head[:len(sig)]), sig) {
+			if r.Marker == "" || bytes.Contains(data, []byte(r.Marker)) {
+				return signatureScore
+			}
+			return signatureOnlyScore
+		}
+	}
+
+	return 0
+}
+
+// mediaType - The lower case media type without parameters e.g. "text/html; charset=utf-8" is "text/html"
+func mediaType(mimeType string) string {
+	if mimeType == "" {
+		return ""
+	}
+
+	mt, _, err := mime.ParseMediaType(mimeType)
+	if err != nil {
+		mt = strings.TrimSpace(strings.Split(mimeType, ";")[0])
+	}
+
+	return strings.ToLower(mt)
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
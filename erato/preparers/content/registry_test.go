@@ -0,0 +1,118 @@
+package content
+
+import (
+	"errors"
+	"testing"
+)
+
+const zipHead = "PK\x03\x04\x14\x00\x06\x00"
+
+func TestMatch(t *testing.T) {
+	tests := []struct {
+		name     string
+		ext      string
+		mimeType string
+		data     string
+		want     string
+		wantErr  error
+	}{
+		{name: "extension", ext: ".pdf", want: ".pdf"},
+		{name: "extension case", ext: ".HTM", want: ".html"},
+		{name: "MIME type", mimeType: "text/markdown", want: ".md"},
+		{name: "MIME type with parameters", mimeType: "Text/HTML; charset=utf-8", want: ".html"},
+		{name: "MIME type over extension", ext: ".txt", mimeType: "application/pdf", want: ".pdf"},
+		{name: "signature over extension and MIME type", ext: ".txt", mimeType: "text/plain", data: "%PDF-1.7", want: ".pdf"},
+		{name: "signature after BOM and white space", data: "\xEF\xBB\xBF \r\n<!DOCTYPE html><html>", want: ".html"},
+		{name: "signature case", data: "<HTML><body>", want: ".html"},
+		{name: "signature and marker", data: zipHead + "xl/workbook.xml", want: ".xlsx"},
+		{name: "signature and marker over extension", ext: ".docx", data: zipHead + "ppt/presentation.xml", want: ".pptx"},
+		{name: "signature and marker over MIME type", mimeType: "application/octet-stream", data: zipHead + "word/document.xml", want: ".docx"},
+		{name: "bare zip head with the extension", ext: ".xlsx", data: zipHead, want: ".xlsx"},
+		{name: "bare zip head with the MIME type", mimeType: "application/vnd.openxmlformats-officedocument.presentationml.presentation", data: zipHead, want: ".pptx"},
+		{name: "bare zip head does not beat the MIME type", mimeType: "text/plain", data: zipHead, want: ".txt"},
+		{name: "bare zip head alone", data: zipHead, wantErr: ErrUnsupportedType},
+		{name: "zip file", ext: ".zip", mimeType: "application/zip", data: zipHead + "readme.txt", wantErr: ErrUnsupportedType},
+		{name: "zip based file of another type", ext: ".odt", mimeType: "application/vnd.oasis.opendocument.text", data: zipHead + "mimetype", wantErr: ErrUnsupportedType},
+		{name: "nothing", wantErr: ErrUnsupportedType},
+		{name: "unknown extension", ext: ".exe", wantErr: ErrUnsupportedType},
+		{name: "unknown MIME type", mimeType: "application/octet-stream", wantErr: ErrUnsupportedType},
+		{name: "text without a signature", data: "hello world", wantErr: ErrUnsupportedType},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r, err := Match(tt.ext, tt.mimeType, []byte(tt.data))
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Match() error = %v, want %v", err, tt.wantErr)
+			}
+			if r.FileExt != tt.want {
+				t.Errorf("Match() = %q, want %q", r.FileExt, tt.want)
+			}
+		})
+	}
+}
+
+func TestSniff(t *testing.T) {
+	tests := []struct {
+		name    string
+		data    string
+		want    string
+		wantErr error
+	}{
+		{name: "docx", data: zipHead + "word/document.xml", want: ".docx"},
+		{name: "pptx", data: zipHead + "ppt/presentation.xml", want: ".pptx"},
+		{name: "xlsx", data: zipHead + "xl/workbook.xml", want: ".xlsx"},
+		{name: "pdf", data: "%PDF-1.4", want: ".pdf"},
+		{name: "html", data: "  <!doctype html>", want: ".html"},
+		{name: "bare zip head", data: zipHead, wantErr: ErrUnsupportedType},
+		{name: "marker without the signature", data: "word/document.xml", wantErr: ErrUnsupportedType},
+		{name: "plain text", data: "hello world", wantErr: ErrUnsupportedType},
+		{name: "empty", data: "", wantErr: ErrUnsupportedType},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r, err := Sniff([]byte(tt.data))
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Sniff() error = %v, want %v", err, tt.wantErr)
+			}
+			if r.FileExt != tt.want {
+				t.Errorf("Sniff() = %q, want %q", r.FileExt, tt.want)
+			}
+		})
+	}
+}
+
+func TestMediaType(t *testing.T) {
+	tests := []struct {
+		mimeType string
+		want     string
+	}{
+		{"", ""},
+		{"text/html", "text/html"},
+		{"Text/HTML; charset=UTF-8", "text/html"},
+		{"application/pdf;", "application/pdf"},
+		{" text/plain ; bad=\"", "text/plain"},
+	}
+
+	for _, tt := range tests {
+		if got := mediaType(tt.mimeType); got != tt.want {
+			t.Errorf("mediaType(%q) = %q, want %q", tt.mimeType, got, tt.want)
+		}
+	}
+}
+
+func TestDocumentExtensions(t *testing.T) {
+	exts := DocumentExtensions()
+
+	for _, want := range []string{".docx", ".pptx", ".xlsx", ".pdf", ".txt", ".md", ".csv", ".json"} {
+		if !contains(exts, want) {
+			t.Errorf("DocumentExtensions() = %q, missing %q", exts, want)
+		}
+	}
+	for _, page := range []string{".html", ".htm", ".xhtml"} {
+		if contains(exts, page) {
+			t.Errorf("DocumentExtensions() = %q, has the web page extension %q", exts, page)
+		}
+	}
+}
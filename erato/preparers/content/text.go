@@ -0,0 +1,330 @@
+package content
+
+import (
+	"Erato/erato/models"
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+)
+
+// Type of Content for plain text and data exports
+type TXT struct{ Config }
+type MD struct{ Config }
+type CSV struct{ Config }
+type JSON struct{ Config }
+
+var utf8BOM = []byte{0xEF, 0xBB, 0xBF}
+
+// Prepare - Takes plain text and converts to text chunks
+func (dt TXT) Prepare(docData *[]byte) ([]string, error) {
+	chunks, _, err := dt.PrepareChunks(docData)
+	return chunks, err
+}
+
+// PrepareChunks - Paragraphs (split on blank lines) are kept together where they fit in a chunk
+func (dt TXT) PrepareChunks(docData *[]byte) ([]string, []models.ChunkMetaData, error) {
+	cb, err := newChunkBuilder(dt.Config)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	for _, para := range paragraphs(textLines(docData)) {
+		cb.add(para)
+	}
+
+	chunks, chunksMetaData := cb.result()
+
+	return chunks, chunksMetaData, nil
+}
+
+// Prepare - Takes Markdown and converts to text chunks
+func (dt MD) Prepare(docData *[]byte) ([]string, error) {
+	chunks, _, err := dt.PrepareChunks(docData)
+	return chunks, err
+}
+
+// PrepareChunks - Chunks start at each heading and keep the heading as their Section
+func (dt MD) PrepareChunks(docData *[]byte) ([]string, []models.ChunkMetaData, error) {
+	return prepareMarkdown(dt.Config, textLines(docData))
+}
+
+var mdHeading = regexp.MustCompile(`^ {0,3}(#{1,6})\s+(.*?)\s*#*\s*$`)
+var mdFence = regexp.MustCompile("^ {0,3}(```|~~~)")
+var mdLink = regexp.MustCompile(`!?\[([^\]]*)\]\([^)]*\)`)
+
+// prepareMarkdown - Split the Markdown into sections at each heading
+// Every chunk starts with the breadcrumb of the headings above it e.g. Products > Pricing
+// so it keeps its context when a section is split on size
+// Headings inside fenced code blocks are left as text
+func prepareMarkdown(c Config, lines []string) ([]string, []models.ChunkMetaData, error) {
+	cb, err := newChunkBuilder(c)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var held []string
+	inFence := false
+
+	// The headings above the current line by level
+	var breadcrumb [6]string
+	// The heading is kept with the first paragraph of its section
+	heading := ""
+
+	addHeld := func() {
+		paras := paragraphs(held)
+		if heading != "" {
+			if len(paras) > 0 {
+				paras[0] = heading + "\n\n" + paras[0]
+			} else {
+				paras = []string{heading}
+			}
+		}
+
+		for _, para := range paras {
+			cb.add(para)
+		}
+		held = nil
+		heading = ""
+	}
+
+	for _, line := range lines {
+		if mdFence.MatchString(line) {
+			inFence = !inFence
+		}
+
+		if m := mdHeading.FindStringSubmatch(line); m != nil && !inFence {
+			addHeld()
+
+			level := len(m[1])
+			title := plainHeading(m[2])
+			breadcrumb[level-1] = title
+			for i := level; i < len(breadcrumb); i++ {
+				breadcrumb[i] = ""
+			}
+
+			var crumbs []string
+			for _, crumb := range breadcrumb[:level] {
+				if crumb != "" {
+					crumbs = append(crumbs, crumb)
+				}
+			}
+
+			cb.contextSection(models.ChunkMetaData{Section: title}, strings.Join(crumbs, " > "))
+			heading = markdownHeading(level, m[2])
+			continue
+		}
+
+		held = append(held, line)
+	}
+	addHeld()
+
+	chunks, chunksMetaData := cb.result()
+
+	return chunks, chunksMetaData, nil
+}
+
+// plainHeading - The heading text without links or emphasis
+func plainHeading(heading string) string {
+	heading = mdLink.ReplaceAllString(heading, "$1")
+	heading = strings.NewReplacer("**", "", "__", "", "`", "").Replace(heading)
+	return strings.TrimSpace(heading)
+}
+
+// Prepare - Takes CSV data and converts to text chunks
+func (dt CSV) Prepare(docData *[]byte) ([]string, error) {
+	chunks, _, err := dt.PrepareChunks(docData)
+	return chunks, err
+}
+
+// PrepareChunks - The first record is the header, the other records are rendered as
+// Markdown tables in batches of rows with the header repeated
+func (dt CSV) PrepareChunks(docData *[]byte) ([]string, []models.ChunkMetaData, error) {
+	if docData == nil {
+		return nil, nil, fmt.Errorf("convertCSVToText-no data")
+	}
+
+	r := csv.NewReader(bytes.NewReader(bytes.TrimPrefix(*docData, utf8BOM)))
+	r.FieldsPerRecord = -1
+	r.LazyQuotes = true
+
+	records, err := r.ReadAll()
+	if err != nil {
+		return nil, nil, &PrepareWarning{FileType: ".csv", Err: err}
+	}
+
+	cb, err := newChunkBuilder(dt.Config)
+	if err != nil {
+		return nil, nil, err
+	}
+	cb.addTable(records)
+
+	chunks, chunksMetaData := cb.result()
+
+	return chunks, chunksMetaData, nil
+}
+
+// Prepare - Takes JSON data and converts to text chunks
+func (dt JSON) Prepare(docData *[]byte) ([]string, error) {
+	chunks, _, err := dt.PrepareChunks(docData)
+	return chunks, err
+}
+
+// PrepareChunks - Flattens the JSON into "path: value" lines e.g. articles[0].title: Welcome
+// The lines for each top level value are kept together where they fit in a chunk
+func (dt JSON) PrepareChunks(docData *[]byte) ([]string, []models.ChunkMetaData, error) {
+	if docData == nil {
+		return nil, nil, fmt.Errorf("convertJSONToText-no data")
+	}
+
+	dec := json.NewDecoder(bytes.NewReader(bytes.TrimPrefix(*docData, utf8BOM)))
+	dec.UseNumber()
+
+	cb, err := newChunkBuilder(dt.Config)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// Dumps are often a list of records or a JSON lines file so each top level
+	// value, or each item of a top level array, is a block
+	for {
+		tok, err := dec.Token()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return nil, nil, &PrepareWarning{FileType: ".json", Err: err}
+		}
+
+		var lines []string
+		switch tok {
+		case json.Delim('['):
+			for i := 0; dec.More(); i++ {
+				lines = nil
+				err = flattenJSON(dec, fmt.Sprintf("[%v]", i), &lines)
+				if err != nil {
+					return nil, nil, &PrepareWarning{FileType: ".json", Err: err}
+				}
+				cb.add(strings.Join(lines, "\n"))
+			}
+			// closing ]
+			_, err = dec.Token()
+
+		case json.Delim('{'):
+			for dec.More() {
+				lines = nil
+				key, err := dec.Token()
+				if err != nil {
+					return nil, nil, &PrepareWarning{FileType: ".json", Err: err}
+				}
+				err = flattenJSON(dec, fmt.Sprint(key), &lines)
+				if err != nil {
+					return nil, nil, &PrepareWarning{FileType: ".json", Err: err}
+				}
+				cb.add(strings.Join(lines, "\n"))
+			}
+			// closing }
+			_, err = dec.Token()
+
+		default:
+			cb.add(jsonValue(tok))
+		}
+
+		if err != nil {
+			return nil, nil, &PrepareWarning{FileType: ".json", Err: err}
+		}
+	}
+
+	chunks, chunksMetaData := cb.result()
+
+	return chunks, chunksMetaData, nil
+}
+
+// flattenJSON - Read the next value from the decoder adding a "path: value" line for each scalar
+func flattenJSON(dec *json.Decoder, path string, lines *[]string) error {
+	tok, err := dec.Token()
+	if err != nil {
+		return err
+	}
+
+	switch tok {
+	case json.Delim('['):
+		for i := 0; dec.More(); i++ {
+			err = flattenJSON(dec, fmt.Sprintf("%v[%v]", path, i), lines)
+			if err != nil {
+				return err
+			}
+		}
+		_, err = dec.Token()
+		return err
+
+	case json.Delim('{'):
+		for dec.More() {
+			key, err := dec.Token()
+			if err != nil {
+				return err
+			}
+			err = flattenJSON(dec, path+"."+fmt.Sprint(key), lines)
+			if err != nil {
+				return err
+			}
+		}
+		_, err = dec.Token()
+		return err
+	}
+
+	value := jsonValue(tok)
+	if value != "" {
+		*lines = append(*lines, path+": "+value)
+	}
+	return nil
+}
+
+// jsonValue - Text of a scalar JSON token, nulls and empty strings are dropped
+func jsonValue(tok json.Token) string {
+	switch v := tok.(type) {
+	case nil:
+		return ""
+	case string:
+		return strings.Join(strings.Fields(v), " ")
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+// textLines - The lines of the text without the byte order mark or carriage returns
+func textLines(docData *[]byte) []string {
+	if docData == nil {
+		return nil
+	}
+
+	text := string(bytes.TrimPrefix(*docData, utf8BOM))
+	text = strings.ReplaceAll(text, "\r\n", "\n")
+
+	return strings.Split(text, "\n")
+}
+
+// paragraphs - Join the lines into paragraphs at each blank line
+func paragraphs(lines []string) []string {
+	var paras []string
+	var para []string
+
+	for _, line := range lines {
+		if strings.TrimSpace(line) == "" {
+			if len(para) > 0 {
+				paras = append(paras, strings.Join(para, "\n"))
+			}
+			para = nil
+			continue
+		}
+		para = append(para, strings.TrimRight(line, " \t"))
+	}
+
+	if len(para) > 0 {
+		paras = append(paras, strings.Join(para, "\n"))
+	}
+
+	return paras
+}
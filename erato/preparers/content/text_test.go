@@ -0,0 +1,186 @@
+package content
+
+import (
+	"Erato/erato/models"
+	"reflect"
+	"testing"
+)
+
+func TestParagraphs(t *testing.T) {
+	tests := []struct {
+		name  string
+		lines []string
+		want  []string
+	}{
+		{"empty", nil, nil},
+		{"blank lines only", []string{"", "  ", "\t"}, nil},
+		{"one paragraph", []string{"one", "two"}, []string{"one\ntwo"}},
+		{"split on blank lines", []string{"one", "", "", "two", " ", "three"}, []string{"one", "two", "three"}},
+		{"trailing space trimmed", []string{"one  ", "two\t"}, []string{"one\ntwo"}},
+		{"leading space kept", []string{"  indented"}, []string{"  indented"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := paragraphs(tt.lines); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("paragraphs() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTXTPrepareChunks(t *testing.T) {
+	tests := []struct {
+		name string
+		c    Config
+		doc  string
+		want []string
+	}{
+		{
+			name: "paragraphs share a chunk",
+			c:    Config{ParagraphMaxWordCount: 10},
+			doc:  "one two\r\n\r\nthree four\r\n",
+			want: []string{"one two\n\nthree four"},
+		},
+		{
+			name: "byte order mark dropped",
+			c:    Config{ParagraphMaxWordCount: 10},
+			doc:  "\xEF\xBB\xBFone two",
+			want: []string{"one two"},
+		},
+		{
+			name: "paragraphs split on the limit",
+			c:    Config{ParagraphMaxWordCount: 3},
+			doc:  "one two\n\nthree four\n\nfive six seven eight",
+			want: []string{"one two", "three four five", "six seven eight"},
+		},
+		{
+			name: "empty",
+			c:    Config{ParagraphMaxWordCount: 10},
+			doc:  "",
+			want: nil,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			doc := []byte(tt.doc)
+			got, _, err := TXT{tt.c}.PrepareChunks(&doc)
+			if err != nil {
+				t.Fatalf("PrepareChunks() error = %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("PrepareChunks() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMDPrepareChunks(t *testing.T) {
+	tests := []struct {
+		name     string
+		c        Config
+		doc      string
+		want     []string
+		wantMeta []models.ChunkMetaData
+	}{
+		{
+			name:     "text before the first heading",
+			c:        Config{ParagraphMaxWordCount: 20},
+			doc:      "Some intro text",
+			want:     []string{"Some intro text"},
+			wantMeta: []models.ChunkMetaData{{}},
+		},
+		{
+			name: "heading kept with its section",
+			c:    Config{ParagraphMaxWordCount: 20},
+			doc:  "# Products\n\nWe sell things.\n\n# Pricing\n\nThings cost money.",
+			want: []string{
+				"Products\n\n# Products\n\nWe sell things.",
+				"Pricing\n\n# Pricing\n\nThings cost money.",
+			},
+			wantMeta: []models.ChunkMetaData{{Section: "Products"}, {Section: "Pricing"}},
+		},
+		{
+			name:     "closing hashes dropped",
+			c:        Config{ParagraphMaxWordCount: 20},
+			doc:      "## Pricing ##\n\ntext",
+			want:     []string{"Pricing\n\n## Pricing\n\ntext"},
+			wantMeta: []models.ChunkMetaData{{Section: "Pricing"}},
+		},
+		{
+			name: "breadcrumb of the headings above",
+			c:    Config{ParagraphMaxWordCount: 20},
+			doc:  "# Products\n\n## Pricing\n\nThings cost money.\n\n## Support\n\nWe help.",
+			want: []string{
+				"Products\n\n# Products",
+				"Products > Pricing\n\n## Pricing\n\nThings cost money.",
+				"Products > Support\n\n## Support\n\nWe help.",
+			},
+			wantMeta: []models.ChunkMetaData{{Section: "Products"}, {Section: "Pricing"}, {Section: "Support"}},
+		},
+		{
+			name: "deeper headings cleared by a higher one",
+			c:    Config{ParagraphMaxWordCount: 20},
+			doc:  "# A\n\n## B\n\n### C\n\ntext\n\n## D\n\nmore",
+			want: []string{
+				"A\n\n# A",
+				"A > B\n\n## B",
+				"A > B > C\n\n### C\n\ntext",
+				"A > D\n\n## D\n\nmore",
+			},
+			wantMeta: []models.ChunkMetaData{{Section: "A"}, {Section: "B"}, {Section: "C"}, {Section: "D"}},
+		},
+		{
+			name: "links and emphasis left out of the section",
+			c:    Config{ParagraphMaxWordCount: 20},
+			doc:  "## **Read** the [docs](https://example.com) `now` ##\n\ntext",
+			want: []string{
+				"Read the docs now\n\n## **Read** the [docs](https://example.com) `now`\n\ntext",
+			},
+			wantMeta: []models.ChunkMetaData{{Section: "Read the docs now"}},
+		},
+		{
+			name: "headings inside code fences left as text",
+			c:    Config{ParagraphMaxWordCount: 20},
+			doc:  "# Setup\n\n```sh\n# install it\nmake\n```\n\n~~~\n## not a heading\n~~~",
+			want: []string{
+				"Setup\n\n# Setup\n\n```sh\n# install it\nmake\n```\n\n~~~\n## not a heading\n~~~",
+			},
+			wantMeta: []models.ChunkMetaData{{Section: "Setup"}},
+		},
+		{
+			name:     "not a heading without a space",
+			c:        Config{ParagraphMaxWordCount: 20},
+			doc:      "#hashtag text",
+			want:     []string{"#hashtag text"},
+			wantMeta: []models.ChunkMetaData{{}},
+		},
+		{
+			name: "section split on the limit less the breadcrumb",
+			c:    Config{ParagraphMaxWordCount: 6},
+			doc:  "# Title\n\none two three four five six",
+			want: []string{
+				"Title\n\n# Title one two three",
+				"Title\n\nfour five six",
+			},
+			wantMeta: []models.ChunkMetaData{{Section: "Title"}, {Section: "Title"}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			doc := []byte(tt.doc)
+			got, meta, err := MD{tt.c}.PrepareChunks(&doc)
+			if err != nil {
+				t.Fatalf("PrepareChunks() error = %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("PrepareChunks() = %q, want %q", got, tt.want)
+			}
+			if !reflect.DeepEqual(meta, tt.wantMeta) {
+				t.Errorf("metaData = %+v, want %+v", meta, tt.wantMeta)
+			}
+		})
+	}
+}
@@ -1,11 +1,16 @@
 package docx
 
 import (
+	"Erato/erato/preparers/ooxml"
 	"archive/zip"
 	"bytes"
 	"encoding/xml"
 	"errors"
+	"fmt"
 	"io"
+	"sort"
+	"strconv"
+	"strings"
 )
 
 var ErrNotSupportFormat = errors.New("the file is not supported")
@@ -40,6 +45,55 @@ type Reader struct {
 	docx *zip.Reader
 	xml  io.ReadCloser
 	dec  *xml.Decoder
+	// Name of the main document part
+	docName string
+	// Heading level and list numbering of the paragraph styles
+	styles map[string]style
+	// numId -> ilvl -> numFmt
+	numbering map[string]map[string]string
+}
+
+// BlockType - The kind of structure a Block represents
+type BlockType string
+
+const (
+	BlockHeading   BlockType = "heading"
+	BlockParagraph BlockType = "paragraph"
+	BlockTable     BlockType = "table"
+	BlockListItem  BlockType = "list item"
+)
+
+// Where in the package the Block was found
+const (
+	PartBody     = "body"
+	PartHeader   = "header"
+	PartFooter   = "footer"
+	PartFootnote = "footnote"
+	PartComment  = "comment"
+)
+
+// Block - A typed piece of the document
+type Block struct {
+	Type BlockType
+	// Heading level (1-9) or the list indent level (0 based)
+	Level int
+	// List is numbered rather than bulleted
+	Ordered bool
+	Text    string
+	// Table rows of cells
+	Rows [][]string
+	Part string
+	// Footnote id or the comment author
+	Ref string
+}
+
+// style - the parts of a paragraph style that give it structure
+type style struct {
+	name         string
+	basedOn      string
+	outlineLevel int // 0 when not set, otherwise 1-9
+	numID        string
+	numLevel     string
 }
 
 // NewReader generetes a Reader struct.
@@ -68,10 +122,15 @@ func NewReader(docxLoc *[]byte) (*Reader, error) {
 			if err != nil {
 				return nil, err
 			}
+			r.docName = f.Name
 			break
 		}
 	}
 
+	if rc == nil {
+		return nil, ErrNotSupportFormat
+	}
+
 	r.docx = a
 	r.xml = rc
 	r.dec = xml.NewDecoder(rc)
@@ -109,7 +168,9 @@ func (r *Reader) ReadAll() ([]string, error) {
 }
 
 func (r *Reader) Close() error {
-	r.xml.Close()
+	if r.xml != nil {
+		r.xml.Close()
+	}
 
 	// Shouln't need this close as it's a []byte
 	// r.docx.Close()
@@ -175,3 +236,354 @@ func seekNextTag(dec *xml.Decoder, tag string) error {
 	}
 	return nil
 }
+
+// CoreProperties - The author, title and dates of the document
+func (r *Reader) CoreProperties() (ooxml.CoreProperties, error) {
+	return ooxml.ReadCoreProperties(r.docx)
+}
+
+// ReadBlocks reads the whole .docx file as typed blocks.
+// The body comes first followed by the footnotes, comments, headers and footers.
+func (r *Reader) ReadBlocks() ([]Block, error) {
+	var blocks []Block
+
+	err := r.readStyles()
+	if err != nil {
+		return nil, fmt.Errorf("ReadBlocks - styles - %v", err)
+	}
+
+	err = r.readNumbering()
+	if err != nil {
+		return nil, fmt.Errorf("ReadBlocks - numbering - %v", err)
+	}
+
+	doc, err := ooxml.ReadNode(r.docx, r.docName)
+	if err != nil {
+		return nil, fmt.Errorf("ReadBlocks - %v - %v", r.docName, err)
+	}
+
+	body := doc.Child("body")
+	if body == nil {
+		return nil, ErrNotSupportFormat
+	}
+	blocks = append(blocks, r.readContainer(body, PartBody)...)
+
+	// Footnotes - the separator notes have a type and no content
+	if notes, err := ooxml.ReadNode(r.docx, "word/footnotes.xml"); err == nil {
+		for _, note := range notes.Children("footnote") {
+			if note.Attr("type") != "" {
+				continue
+			}
+			for _, b := range r.readContainer(note, PartFootnote) {
+				b.Ref = note.Attr("id")
+				blocks = append(blocks, b)
+			}
+		}
+	}
+
+	// Comments
+	if comments, err := ooxml.ReadNode(r.docx, "word/comments.xml"); err == nil {
+		for _, comment := range comments.Children("comment") {
+			for _, b := range r.readContainer(comment, PartComment) {
+				b.Ref = comment.Attr("author")
+				blocks = append(blocks, b)
+			}
+		}
+	}
+
+	// Headers and footers - the same text is often repeated across the sections
+	seen := make(map[string]bool)
+	for _, part := range []string{PartHeader, PartFooter} {
+		names := ooxml.PartNames(r.docx, "word/"+part)
+		sort.Strings(names)
+		for _, name := range names {
+			n, err := ooxml.ReadNode(r.docx, name)
+			if err != nil {
+				continue
+			}
+			for _, b := range r.readContainer(n, part) {
+				key := part + b.Text + fmt.Sprint(b.Rows)
+				if seen[key] {
+					continue
+				}
+				seen[key] = true
+				blocks = append(blocks, b)
+			}
+		}
+	}
+
+	return blocks, nil
+}
+
+// readContainer - Blocks from an element that holds paragraphs and tables
+// e.g. the body, a footnote, a comment or a header
+func (r *Reader) readContainer(n *ooxml.Node, part string) []Block {
+	var blocks []Block
+
+	for i := range n.Nodes {
+		child := &n.Nodes[i]
+
+		switch child.XMLName.Local {
+		case "p":
+			b, ok := r.readParagraph(child, part)
+			if ok {
+				blocks = append(blocks, b)
+			}
+
+		case "tbl":
+			b := Block{Type: BlockTable, Rows: readTable(child), Part: part}
+			if len(b.Rows) > 0 {
+				blocks = append(blocks, b)
+			}
+
+		case "sdt":
+			// Content controls - the table of contents repeats the headings so is skipped
+			if gallery := child.Find("sdtPr", "docPartObj", "docPartGallery"); gallery != nil &&
+				gallery.Attr("val") == "Table of Contents" {
+				continue
+			}
+			if content := child.Child("sdtContent"); content != nil {
+				blocks = append(blocks, r.readContainer(content, part)...)
+			}
+
+		case "customXml", "ins", "smartTag":
+			blocks = append(blocks, r.readContainer(child, part)...)
+		}
+	}
+
+	return blocks
+}
+
+// readParagraph - Type the paragraph from its style and numbering
+func (r *Reader) readParagraph(p *ooxml.Node, part string) (Block, bool) {
+	b := Block{Type: BlockParagraph, Part: part}
+
+	b.Text = strings.TrimSpace(paragraphText(p))
+	if b.Text == "" {
+		return b, false
+	}
+
+	var styleID, numID, numLevel string
+	outlineLevel := 0
+
+	if pPr := p.Child("pPr"); pPr != nil {
+		if ps := pPr.Child("pStyle"); ps != nil {
+			styleID = ps.Attr("val")
+		}
+		if ol := pPr.Child("outlineLvl"); ol != nil {
+			outlineLevel = atoi(ol.Attr("val")) + 1
+		}
+		if numPr := pPr.Child("numPr"); numPr != nil {
+			if id := numPr.Child("numId"); id != nil {
+				numID = id.Attr("val")
+			}
+			if lvl := numPr.Child("ilvl"); lvl != nil {
+				numLevel = lvl.Attr("val")
+			}
+		}
+	}
+
+	// Table of contents entries repeat the headings
+	if strings.HasPrefix(styleID, "TOC") {
+		return b, false
+	}
+
+	// Fill in the gaps from the style hierarchy
+	st := r.resolveStyle(styleID)
+	if outlineLevel == 0 {
+		outlineLevel = st.outlineLevel
+	}
+	if numID == "" {
+		numID = st.numID
+	}
+	if numLevel == "" {
+		numLevel = st.numLevel
+	}
+
+	switch {
+	case outlineLevel > 0 && outlineLevel <= 9:
+		b.Type = BlockHeading
+		b.Level = outlineLevel
+
+	case numID != "" && numID != "0":
+		b.Type = BlockListItem
+		b.Level = atoi(numLevel)
+		fmtName := r.numbering[numID][strconv.Itoa(b.Level)]
+		b.Ordered = fmtName != "" && fmtName != "bullet" && fmtName != "none"
+	}
+
+	return b, true
+}
+
+// paragraphText - Text of the runs in the paragraph, hyperlinks and text boxes included
+func paragraphText(p *ooxml.Node) string {
+	var sb strings.Builder
+
+	p.Walk(func(n *ooxml.Node) bool {
+		switch n.XMLName.Local {
+		case "t":
+			sb.WriteString(n.Text)
+		case "tab":
+			sb.WriteString("\t")
+		case "br", "cr":
+			sb.WriteString(" ")
+		case "footnoteReference":
+			sb.WriteString("[^" + n.Attr("id") + "]")
+		case "p":
+			// Paragraphs nested in text boxes
+			if sb.Len() > 0 {
+				sb.WriteString(" ")
+			}
+		case "Fallback", "delText", "instrText", "pPr", "rPr":
+			// Fallback duplicates the AlternateContent Choice
+			return false
+		}
+		return true
+	})
+
+	return sb.String()
+}
+
+// readTable - The rows of cells with the cell paragraphs joined
+func readTable(tbl *ooxml.Node) [][]string {
+	var rows [][]string
+
+	for _, tr := range tbl.Children("tr") {
+		var row []string
+		for _, tc := range tr.Children("tc") {
+			var parts []string
+			tc.Walk(func(n *ooxml.Node) bool {
+				if n.XMLName.Local == "p" {
+					if t := strings.TrimSpace(paragraphText(n)); t != "" {
+						parts = append(parts, t)
+					}
+					return false
+				}
+				return true
+			})
+			row = append(row, strings.Join(parts, " "))
+		}
+
+		if strings.TrimSpace(strings.Join(row, "")) == "" {
+			continue
+		}
+		rows = append(rows, row)
+	}
+
+	return rows
+}
+
+// readStyles - Heading levels and numbering from word/styles.xml
+func (r *Reader) readStyles() error {
+	r.styles = make(map[string]style)
+
+	n, err := ooxml.ReadNode(r.docx, "word/styles.xml")
+	if err == ooxml.ErrPartNotFound {
+		return nil
+	} else if err != nil {
+		return err
+	}
+
+	for _, s := range n.Children("style") {
+		if s.Attr("type") != "paragraph" {
+			continue
+		}
+
+		st := style{}
+		if name := s.Child("name"); name != nil {
+			st.name = strings.ToLower(name.Attr("val"))
+		}
+		if basedOn := s.Child("basedOn"); basedOn != nil {
+			st.basedOn = basedOn.Attr("val")
+		}
+
+		if pPr := s.Child("pPr"); pPr != nil {
+			if ol := pPr.Child("outlineLvl"); ol != nil {
+				st.outlineLevel = atoi(ol.Attr("val")) + 1
+			}
+			if numPr := pPr.Child("numPr"); numPr != nil {
+				if id := numPr.Child("numId"); id != nil {
+					st.numID = id.Attr("val")
+				}
+				if lvl := numPr.Child("ilvl"); lvl != nil {
+					st.numLevel = lvl.Attr("val")
+				}
+			}
+		}
+
+		// The built in names are more reliable than the outline level
+		switch {
+		case st.name == "title":
+			st.outlineLevel = 1
+		case strings.HasPrefix(st.name, "heading "):
+			if lvl := atoi(strings.TrimPrefix(st.name, "heading ")); lvl > 0 {
+				st.outlineLevel = lvl
+			}
+		}
+
+		r.styles[s.Attr("styleId")] = st
+	}
+
+	return nil
+}
+
+// resolveStyle - Merge the style with the styles it is based on
+func (r *Reader) resolveStyle(id string) style {
+	var st style
+
+	// Guard against loops in the basedOn chain
+	for i := 0; id != "" && i < 10; i++ {
+		s, ok := r.styles[id]
+		if !ok {
+			break
+		}
+		if st.outlineLevel == 0 {
+			st.outlineLevel = s.outlineLevel
+		}
+		if st.numID == "" {
+			st.numID = s.numID
+		}
+		if st.numLevel == "" {
+			st.numLevel = s.numLevel
+		}
+		id = s.basedOn
+	}
+
+	return st
+}
+
+// readNumbering - The number format of each list level from word/numbering.xml
+func (r *Reader) readNumbering() error {
+	r.numbering = make(map[string]map[string]string)
+
+	n, err := ooxml.ReadNode(r.docx, "word/numbering.xml")
+	if err == ooxml.ErrPartNotFound {
+		return nil
+	} else if err != nil {
+		return err
+	}
+
+	abstract := make(map[string]map[string]string)
+	for _, an := range n.Children("abstractNum") {
+		levels := make(map[string]string)
+		for _, lvl := range an.Children("lvl") {
+			if nf := lvl.Child("numFmt"); nf != nil {
+				levels[lvl.Attr("ilvl")] = nf.Attr("val")
+			}
+		}
+		abstract[an.Attr("abstractNumId")] = levels
+	}
+
+	for _, num := range n.Children("num") {
+		if an := num.Child("abstractNumId"); an != nil {
+			r.numbering[num.Attr("numId")] = abstract[an.Attr("val")]
+		}
+	}
+
+	return nil
+}
+
+func atoi(s string) int {
+	i, _ := strconv.Atoi(s)
+	return i
+}
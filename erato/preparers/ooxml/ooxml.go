@@ -0,0 +1,205 @@
+package ooxml
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"io"
+	"path"
+	"strings"
+	"time"
+)
+
+// Helpers shared by the Office Open XML readers (.docx, .pptx, .xlsx)
+
+var ErrPartNotFound = errors.New("the part is not in the package")
+
+// Node - Generic XML element so the readers can walk the parts without a struct per schema
+type Node struct {
+	XMLName xml.Name
+	Attrs   []xml.Attr `xml:",any,attr"`
+	Text    string     `xml:",chardata"`
+	Nodes   []Node     `xml:",any"`
+}
+
+// CoreProperties - Values from docProps/core.xml
+type CoreProperties struct {
+	Title          string
+	Subject        string
+	Creator        string
+	Keywords       string
+	Description    string
+	LastModifiedBy string
+	Created        time.Time
+	Modified       time.Time
+}
+
+// NewZipReader - Open the package from the downloaded data
+func NewZipReader(data *[]byte) (*zip.Reader, error) {
+	if data == nil {
+		return nil, errors.New("no data")
+	}
+	return zip.NewReader(bytes.NewReader(*data), int64(len(*data)))
+}
+
+// ReadPart - Read a part (file) from the package
+func ReadPart(zr *zip.Reader, name string) ([]byte, error) {
+	for _, f := range zr.File {
+		if f.Name != name {
+			continue
+		}
+
+		rc, err := f.Open()
+		if err != nil {
+			return nil, err
+		}
+		defer rc.Close()
+
+		return io.ReadAll(rc)
+	}
+
+	return nil, ErrPartNotFound
+}
+
+// ReadNode - Read a part and parse it into a Node tree
+func ReadNode(zr *zip.Reader, name string) (*Node, error) {
+	data, err := ReadPart(zr, name)
+	if err != nil {
+		return nil, err
+	}
+
+	var n Node
+	err = xml.Unmarshal(data, &n)
+	if err != nil {
+		return nil, err
+	}
+
+	return &n, nil
+}
+
+// PartNames - The names of the parts in the package that start with the prefix
+func PartNames(zr *zip.Reader, prefix string) []string {
+	var names []string
+	for _, f := range zr.File {
+		if strings.HasPrefix(f.Name, prefix) {
+			names = append(names, f.Name)
+		}
+	}
+	return names
+}
+
+// ReadRelationships - Map of relationship Id to Target for the part
+// Targets are resolved relative to the part's folder
+func ReadRelationships(zr *zip.Reader, partName string) (map[string]string, error) {
+	dir, file := path.Split(partName)
+	relsName := dir + "_rels/" + file + ".rels"
+
+	rels := make(map[string]string)
+
+	n, err := ReadNode(zr, relsName)
+	if err == ErrPartNotFound {
+		return rels, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	for _, rel := range n.Children("Relationship") {
+		target := rel.Attr("Target")
+		if rel.Attr("TargetMode") != "External" {
+			if strings.HasPrefix(target, "/") {
+				target = strings.TrimPrefix(target, "/")
+			} else {
+				target = path.Join(dir, target)
+			}
+		}
+		rels[rel.Attr("Id")] = target
+	}
+
+	return rels, nil
+}
+
+// ReadCoreProperties - Read the author, title and dates from docProps/core.xml
+func ReadCoreProperties(zr *zip.Reader) (CoreProperties, error) {
+	var cp CoreProperties
+
+	n, err := ReadNode(zr, "docProps/core.xml")
+	if err != nil {
+		return cp, err
+	}
+
+	cp.Title = n.ChildText("title")
+	cp.Subject = n.ChildText("subject")
+	cp.Creator = n.ChildText("creator")
+	cp.Keywords = n.ChildText("keywords")
+	cp.Description = n.ChildText("description")
+	cp.LastModifiedBy = n.ChildText("lastModifiedBy")
+
+	// Dates are W3CDTF - ignore any that don't parse
+	cp.Created, _ = time.Parse(time.RFC3339, n.ChildText("created"))
+	cp.Modified, _ = time.Parse(time.RFC3339, n.ChildText("modified"))
+
+	return cp, nil
+}
+
+// Attr - Value of the attribute with the local name (namespaces are ignored)
+func (n *Node) Attr(local string) string {
+	for _, a := range n.Attrs {
+		if a.Name.Local == local {
+			return a.Value
+		}
+	}
+	return ""
+}
+
+// Child - First child element with the local name or nil
+func (n *Node) Child(local string) *Node {
+	for i := range n.Nodes {
+		if n.Nodes[i].XMLName.Local == local {
+			return &n.Nodes[i]
+		}
+	}
+	return nil
+}
+
+// Children - All the child elements with the local name
+func (n *Node) Children(local string) []*Node {
+	var nodes []*Node
+	for i := range n.Nodes {
+		if n.Nodes[i].XMLName.Local == local {
+			nodes = append(nodes, &n.Nodes[i])
+		}
+	}
+	return nodes
+}
+
+// ChildText - Trimmed text of the first child element with the local name
+func (n *Node) ChildText(local string) string {
+	c := n.Child(local)
+	if c == nil {
+		return ""
+	}
+	return strings.TrimSpace(c.Text)
+}
+
+// Find - Follow a path of local names e.g. Find("pPr", "pStyle")
+func (n *Node) Find(locals ...string) *Node {
+	c := n
+	for _, local := range locals {
+		c = c.Child(local)
+		if c == nil {
+			return nil
+		}
+	}
+	return c
+}
+
+// Walk - Depth first walk of the descendants
+// Return false from the func to stop descending into a node
+func (n *Node) Walk(fn func(*Node) bool) {
+	for i := range n.Nodes {
+		if fn(&n.Nodes[i]) {
+			n.Nodes[i].Walk(fn)
+		}
+	}
+}
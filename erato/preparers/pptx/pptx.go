@@ -0,0 +1,261 @@
+package pptx
+
+import (
+	"Erato/erato/preparers/ooxml"
+	"archive/zip"
+	"errors"
+	"path"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+var ErrNotSupportFormat = errors.New("the file is not supported")
+
+// Slide - The text of a slide and its speaker notes
+type Slide struct {
+	Number int
+	Title  string
+	// Each text paragraph on the slide, indented by its bullet level
+	Body []string
+	// Tables on the slide as rows of cells
+	Tables [][][]string
+	Notes  []string
+}
+
+type Reader struct {
+	pptx *zip.Reader
+}
+
+// NewReader generates a Reader for the PowerPoint data
+func NewReader(pptxData *[]byte) (*Reader, error) {
+	zr, err := ooxml.NewZipReader(pptxData)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := ooxml.ReadPart(zr, "ppt/presentation.xml"); err != nil {
+		return nil, ErrNotSupportFormat
+	}
+
+	return &Reader{pptx: zr}, nil
+}
+
+// CoreProperties - The author, title and dates of the presentation
+func (r *Reader) CoreProperties() (ooxml.CoreProperties, error) {
+	return ooxml.ReadCoreProperties(r.pptx)
+}
+
+// ReadSlides reads all the slides in presentation order
+func (r *Reader) ReadSlides() ([]Slide, error) {
+	var slides []Slide
+
+	names, err := r.slideNames()
+	if err != nil {
+		return nil, err
+	}
+
+	for i, name := range names {
+		slide, err := r.readSlide(name)
+		if err != nil {
+			return nil, err
+		}
+		slide.Number = i + 1
+		slides = append(slides, slide)
+	}
+
+	return slides, nil
+}
+
+// slideNames - The slide parts in the order of the presentation's slide list
+func (r *Reader) slideNames() ([]string, error) {
+	var names []string
+
+	pres, err := ooxml.ReadNode(r.pptx, "ppt/presentation.xml")
+	if err != nil {
+		return nil, err
+	}
+
+	rels, err := ooxml.ReadRelationships(r.pptx, "ppt/presentation.xml")
+	if err != nil {
+		return nil, err
+	}
+
+	if lst := pres.Child("sldIdLst"); lst != nil {
+		for _, sldID := range lst.Children("sldId") {
+			if target, ok := rels[sldID.Attr("id")]; ok {
+				names = append(names, target)
+			}
+		}
+	}
+
+	if len(names) > 0 {
+		return names, nil
+	}
+
+	// Fallback to the number in the part name
+	re := regexp.MustCompile(`^ppt/slides/slide(\d+)\.xml$`)
+	for _, name := range ooxml.PartNames(r.pptx, "ppt/slides/slide") {
+		if re.MatchString(name) {
+			names = append(names, name)
+		}
+	}
+	sort.Slice(names, func(i, j int) bool {
+		ni, _ := strconv.Atoi(re.FindStringSubmatch(names[i])[1])
+		nj, _ := strconv.Atoi(re.FindStringSubmatch(names[j])[1])
+		return ni < nj
+	})
+
+	return names, nil
+}
+
+// readSlide - The title, body text and notes of a slide
+func (r *Reader) readSlide(name string) (Slide, error) {
+	var slide Slide
+
+	n, err := ooxml.ReadNode(r.pptx, name)
+	if err != nil {
+		return slide, err
+	}
+
+	if spTree := n.Find("cSld", "spTree"); spTree != nil {
+		readShapes(spTree, &slide, false)
+	}
+
+	// The notes slide is found through the slide's relationships
+	rels, err := ooxml.ReadRelationships(r.pptx, name)
+	if err != nil {
+		return slide, err
+	}
+
+	for _, target := range rels {
+		if path.Dir(target) != "ppt/notesSlides" {
+			continue
+		}
+
+		notes, err := ooxml.ReadNode(r.pptx, target)
+		if err != nil {
+			continue
+		}
+
+		if spTree := notes.Find("cSld", "spTree"); spTree != nil {
+			var ns Slide
+			readShapes(spTree, &ns, true)
+			slide.Notes = append(slide.Notes, ns.Body...)
+		}
+	}
+
+	return slide, nil
+}
+
+// readShapes - Text from the shapes, groups and tables in a shape tree
+func readShapes(spTree *ooxml.Node, slide *Slide, notes bool) {
+	for i := range spTree.Nodes {
+		shape := &spTree.Nodes[i]
+
+		switch shape.XMLName.Local {
+		case "sp":
+			phType := ""
+			isPlaceholder := false
+			if ph := shape.Find("nvSpPr", "nvPr", "ph"); ph != nil {
+				isPlaceholder = true
+				phType = ph.Attr("type")
+			}
+
+			switch phType {
+			case "sldNum", "dt", "ftr", "hdr", "sldImg":
+				// Slide furniture
+				continue
+			}
+
+			// The notes page repeats the slide title, only the notes body is wanted
+			if notes && isPlaceholder && phType != "body" {
+				continue
+			}
+
+			paras := readTextBody(shape.Child("txBody"))
+			if len(paras) == 0 {
+				continue
+			}
+
+			if !notes && (phType == "title" || phType == "ctrTitle") && slide.Title == "" {
+				slide.Title = strings.TrimSpace(strings.Join(paras, " "))
+				continue
+			}
+
+			slide.Body = append(slide.Body, paras...)
+
+		case "grpSp":
+			readShapes(shape, slide, notes)
+
+		case "graphicFrame":
+			if tbl := shape.Find("graphic", "graphicData", "tbl"); tbl != nil {
+				if rows := readTable(tbl); len(rows) > 0 {
+					slide.Tables = append(slide.Tables, rows)
+				}
+			}
+		}
+	}
+}
+
+// readTextBody - A line for each paragraph, indented by its bullet level
+func readTextBody(txBody *ooxml.Node) []string {
+	var paras []string
+	if txBody == nil {
+		return paras
+	}
+
+	for _, p := range txBody.Children("p") {
+		text := strings.TrimSpace(paragraphText(p))
+		if text == "" {
+			continue
+		}
+
+		level := 0
+		if pPr := p.Child("pPr"); pPr != nil {
+			level, _ = strconv.Atoi(pPr.Attr("lvl"))
+		}
+
+		paras = append(paras, strings.Repeat("  ", level)+text)
+	}
+
+	return paras
+}
+
+// paragraphText - Text of the runs, fields and line breaks in the paragraph
+func paragraphText(p *ooxml.Node) string {
+	var sb strings.Builder
+
+	for i := range p.Nodes {
+		n := &p.Nodes[i]
+		switch n.XMLName.Local {
+		case "r", "fld":
+			if t := n.Child("t"); t != nil {
+				sb.WriteString(t.Text)
+			}
+		case "br":
+			sb.WriteString(" ")
+		}
+	}
+
+	return sb.String()
+}
+
+// readTable - The rows of cells in a table
+func readTable(tbl *ooxml.Node) [][]string {
+	var rows [][]string
+
+	for _, tr := range tbl.Children("tr") {
+		var row []string
+		for _, tc := range tr.Children("tc") {
+			row = append(row, strings.Join(readTextBody(tc.Child("txBody")), " "))
+		}
+		if strings.TrimSpace(strings.Join(row, "")) == "" {
+			continue
+		}
+		rows = append(rows, row)
+	}
+
+	return rows
+}
@@ -0,0 +1,259 @@
+package readability
+
+import (
+	"bytes"
+	"crypto/sha1"
+	"encoding/hex"
+	"errors"
+	"math"
+	"regexp"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+	"github.com/andybalholm/cascadia"
+	"golang.org/x/net/html"
+)
+
+// Readability style extraction of the main content of a web page
+// so the navigation, banners and footers aren't prepared with every page
+
+var ErrNoContent = errors.New("no main content found")
+
+// Extractor - Settings for extracting the main content of the pages of a site
+type Extractor struct {
+	// CSS selectors for the main content - when they match the scoring is skipped
+	Include []string
+	// CSS selectors for elements to always remove e.g. ".cookie-banner"
+	Exclude []string
+	// Hashes of blocks repeated across the pages of the site (from BlockHashes)
+	Repeated map[string]bool
+}
+
+// Elements that are never content - forms are kept as ASP.NET pages wrap the whole page in one
+const removeSelector = "script, style, noscript, template, iframe, svg, canvas, button, input, select, textarea, nav, footer, aside, dialog"
+
+// Landmarks that hold the main content when the page has them
+const mainSelector = "main, [role=main], article"
+
+// Blocks hashed to find text repeated across pages - headings are left as they give the content its structure
+const blockSelector = "p, li, dd, dt, td, th, blockquote, pre, figcaption, address"
+
+var (
+	unlikely = regexp.MustCompile(`(?i)banner|breadcrumb|cookie|consent|combx|comment|community|disqus|extra|foot|header|menu|modal|nav|popup|promo|related|remark|rss|share|shoutbox|sidebar|skip|social|sponsor|subscribe|ad-break|agegate|pagination|pager`)
+	maybe    = regexp.MustCompile(`(?i)and|article|body|column|main|shadow|content`)
+	positive = regexp.MustCompile(`(?i)article|body|content|entry|hentry|main|page|post|text|blog|story`)
+	negative = regexp.MustCompile(`(?i)hidden|banner|combx|comment|com-|contact|foot|footer|footnote|masthead|media|meta|outbrain|promo|related|scroll|share|shoutbox|sidebar|skyscraper|sponsor|shopping|tags|tool|widget|cookie|nav|menu`)
+)
+
+// MainContent - The HTML of the main content of the page
+// Returns ErrNoContent when nothing is left after the boilerplate is removed
+func (e Extractor) MainContent(data []byte) ([]byte, error) {
+	doc, err := goquery.NewDocumentFromReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+
+	title := strings.TrimSpace(doc.Find("title").First().Text())
+
+	doc.Find(removeSelector).Remove()
+	doc.Find("[hidden], [aria-hidden=true], [role=navigation], [role=banner], [role=contentinfo], [role=complementary]").Remove()
+	// The site header but not the header of an article
+	doc.Find("header").Not("main header, article header").Remove()
+	for _, sel := range e.Exclude {
+		doc.Find(sel).Remove()
+	}
+
+	e.removeRepeated(doc.Selection)
+
+	var content *goquery.Selection
+	for _, sel := range e.Include {
+		if s := doc.Find(sel); s.Length() > 0 {
+			content = s
+			break
+		}
+	}
+
+	if content == nil {
+		if s := doc.Find(mainSelector).First(); s.Length() > 0 && wordCount(s.Text()) > 0 {
+			content = s
+		} else {
+			content = topCandidate(doc)
+		}
+	}
+
+	if content == nil || wordCount(content.Text()) == 0 {
+		return nil, ErrNoContent
+	}
+
+	var sb strings.Builder
+	sb.WriteString("<html><head><title>" + html.EscapeString(title) + "</title></head><body>")
+	content.Each(func(i int, s *goquery.Selection) {
+		h, err := goquery.OuterHtml(s)
+		if err == nil {
+			sb.WriteString(h)
+		}
+	})
+	sb.WriteString("</body></html>")
+
+	return []byte(sb.String()), nil
+}
+
+// CheckSelector - Check the CSS selector parses, goquery silently matches nothing when it doesn't
+func CheckSelector(sel string) error {
+	_, err := cascadia.ParseGroup(sel)
+	return err
+}
+
+// BlockHashes - Hashes of the text of each block on the page, each hash once
+// Count them across the pages of a site to find the repeated blocks
+func BlockHashes(data []byte) ([]string, error) {
+	doc, err := goquery.NewDocumentFromReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+
+	seen := make(map[string]bool)
+	var hashes []string
+
+	doc.Find(blockSelector).Each(func(i int, s *goquery.Selection) {
+		if h := blockHash(s); h != "" && !seen[h] {
+			seen[h] = true
+			hashes = append(hashes, h)
+		}
+	})
+
+	return hashes, nil
+}
+
+// removeRepeated - Remove the blocks that are repeated across the site
+func (e Extractor) removeRepeated(s *goquery.Selection) {
+	if len(e.Repeated) == 0 {
+		return
+	}
+
+	s.Find(blockSelector).Each(func(i int, b *goquery.Selection) {
+		if e.Repeated[blockHash(b)] {
+			b.Remove()
+		}
+	})
+}
+
+// blockHash - Hash of the normalised text of the block, blank for empty blocks
+func blockHash(s *goquery.Selection) string {
+	text := strings.ToLower(strings.Join(strings.Fields(s.Text()), " "))
+	if text == "" {
+		return ""
+	}
+
+	sum := sha1.Sum([]byte(text))
+	return hex.EncodeToString(sum[:])
+}
+
+// topCandidate - Score the parents of the paragraphs and return the best one
+// Based on the scoring used by Mozilla's Readability
+func topCandidate(doc *goquery.Document) *goquery.Selection {
+	// Remove the unlikely candidates unless they could also be the content
+	doc.Find("body *").Each(func(i int, s *goquery.Selection) {
+		match := s.AttrOr("class", "") + " " + s.AttrOr("id", "")
+		if unlikely.MatchString(match) && !maybe.MatchString(match) && goquery.NodeName(s) != "body" {
+			s.Remove()
+		}
+	})
+
+	scores := make(map[*html.Node]float64)
+	nodes := make(map[*html.Node]*goquery.Selection)
+	// Document order so ties always pick the same node
+	var order []*html.Node
+
+	addScore := func(s *goquery.Selection, score float64) {
+		if s.Length() == 0 {
+			return
+		}
+		n := s.Get(0)
+		if _, ok := scores[n]; !ok {
+			scores[n] = initialScore(s)
+			nodes[n] = s
+			order = append(order, n)
+		}
+		scores[n] += score
+	}
+
+	doc.Find("p, pre, td, li").Each(func(i int, s *goquery.Selection) {
+		text := strings.TrimSpace(s.Text())
+		if len(text) < 25 {
+			return
+		}
+
+		// A point for the paragraph, one per comma and one per 100 characters up to 3
+		score := 1 + float64(strings.Count(text, ",")) + math.Min(float64(len(text))/100, 3)
+
+		addScore(s.Parent(), score)
+		addScore(s.Parent().Parent(), score/2)
+	})
+
+	var best *goquery.Selection
+	bestScore := 0.0
+	for _, n := range order {
+		s := nodes[n]
+		score := scores[n] * (1 - linkDensity(s))
+		if best == nil || score > bestScore {
+			best = s
+			bestScore = score
+		}
+	}
+
+	if best == nil {
+		return doc.Find("body")
+	}
+
+	return best
+}
+
+// initialScore - Score from the element type and its class and id
+func initialScore(s *goquery.Selection) float64 {
+	score := 0.0
+
+	switch goquery.NodeName(s) {
+	case "div", "main", "article", "section":
+		score += 5
+	case "pre", "td", "blockquote":
+		score += 3
+	case "address", "ol", "ul", "dl", "dd", "dt", "li":
+		score -= 3
+	case "h1", "h2", "h3", "h4", "h5", "h6", "th":
+		score -= 5
+	}
+
+	for _, attr := range []string{s.AttrOr("class", ""), s.AttrOr("id", "")} {
+		if attr == "" {
+			continue
+		}
+		if negative.MatchString(attr) {
+			score -= 25
+		}
+		if positive.MatchString(attr) {
+			score += 25
+		}
+	}
+
+	return score
+}
+
+// linkDensity - Share of the text that is in links
+func linkDensity(s *goquery.Selection) float64 {
+	textLen := len(strings.TrimSpace(s.Text()))
+	if textLen == 0 {
+		return 0
+	}
+
+	linkLen := 0
+	s.Find("a").Each(func(i int, a *goquery.Selection) {
+		linkLen += len(strings.TrimSpace(a.Text()))
+	})
+
+	return float64(linkLen) / float64(textLen)
+}
+
+func wordCount(text string) int {
+	return len(strings.Fields(text))
+}
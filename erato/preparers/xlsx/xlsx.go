@@ -0,0 +1,301 @@
+package xlsx
+
+import (
+	"Erato/erato/preparers/ooxml"
+	"archive/zip"
+	"errors"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+var ErrNotSupportFormat = errors.New("the file is not supported")
+
+// Sheet - A worksheet and the table regions found on it
+type Sheet struct {
+	Name   string
+	Tables []Table
+}
+
+// Table - A block of rows on a sheet, separated from other tables by empty rows
+// The first row is taken as the header
+type Table struct {
+	FirstCol int
+	LastCol  int
+	Rows     []Row
+}
+
+// Row - The cell text from FirstCol to LastCol of the table and the sheet row number
+type Row struct {
+	Number int
+	Cells  []string
+}
+
+type Reader struct {
+	xlsx          *zip.Reader
+	sharedStrings []string
+}
+
+// NewReader generates a Reader for the Excel data
+func NewReader(xlsxData *[]byte) (*Reader, error) {
+	zr, err := ooxml.NewZipReader(xlsxData)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := ooxml.ReadPart(zr, "xl/workbook.xml"); err != nil {
+		return nil, ErrNotSupportFormat
+	}
+
+	r := &Reader{xlsx: zr}
+
+	err = r.readSharedStrings()
+	if err != nil {
+		return nil, err
+	}
+
+	return r, nil
+}
+
+// CoreProperties - The author, title and dates of the workbook
+func (r *Reader) CoreProperties() (ooxml.CoreProperties, error) {
+	return ooxml.ReadCoreProperties(r.xlsx)
+}
+
+// ReadSheets reads the worksheets in workbook order
+func (r *Reader) ReadSheets() ([]Sheet, error) {
+	var sheets []Sheet
+
+	wb, err := ooxml.ReadNode(r.xlsx, "xl/workbook.xml")
+	if err != nil {
+		return nil, err
+	}
+
+	rels, err := ooxml.ReadRelationships(r.xlsx, "xl/workbook.xml")
+	if err != nil {
+		return nil, err
+	}
+
+	sheetsNode := wb.Child("sheets")
+	if sheetsNode == nil {
+		return sheets, nil
+	}
+
+	for _, s := range sheetsNode.Children("sheet") {
+		target, ok := rels[s.Attr("id")]
+		if !ok {
+			continue
+		}
+
+		cells, err := r.readCells(target)
+		if err == ooxml.ErrPartNotFound {
+			// Chart sheets and missing parts have no cells
+			continue
+		} else if err != nil {
+			return nil, err
+		}
+
+		sheets = append(sheets, Sheet{Name: s.Attr("name"), Tables: tables(cells)})
+	}
+
+	return sheets, nil
+}
+
+// readSharedStrings - The workbook's string table that the cells refer to by index
+func (r *Reader) readSharedStrings() error {
+	n, err := ooxml.ReadNode(r.xlsx, "xl/sharedStrings.xml")
+	if err == ooxml.ErrPartNotFound {
+		return nil
+	} else if err != nil {
+		return err
+	}
+
+	for _, si := range n.Children("si") {
+		r.sharedStrings = append(r.sharedStrings, richText(si))
+	}
+
+	return nil
+}
+
+// richText - Text of a shared or inline string, plain <t> or runs of <r><t>
+// Phonetic runs (rPh) are ignored
+func richText(si *ooxml.Node) string {
+	if t := si.Child("t"); t != nil {
+		return t.Text
+	}
+
+	var sb strings.Builder
+	for _, run := range si.Children("r") {
+		if t := run.Child("t"); t != nil {
+			sb.WriteString(t.Text)
+		}
+	}
+	return sb.String()
+}
+
+// readCells - Map of row number to column number to cell text for the non empty cells
+func (r *Reader) readCells(name string) (map[int]map[int]string, error) {
+	cells := make(map[int]map[int]string)
+
+	n, err := ooxml.ReadNode(r.xlsx, name)
+	if err != nil {
+		return nil, err
+	}
+
+	sheetData := n.Child("sheetData")
+	if sheetData == nil {
+		return cells, nil
+	}
+
+	rowNum := 0
+	for _, row := range sheetData.Children("row") {
+		// The row and cell references are optional, default to the next one
+		if ref, err := strconv.Atoi(row.Attr("r")); err == nil {
+			rowNum = ref
+		} else {
+			rowNum++
+		}
+
+		colNum := 0
+		for _, c := range row.Children("c") {
+			if col, _, ok := splitRef(c.Attr("r")); ok {
+				colNum = col
+			} else {
+				colNum++
+			}
+
+			text := strings.TrimSpace(r.cellText(c))
+			if text == "" {
+				continue
+			}
+
+			if cells[rowNum] == nil {
+				cells[rowNum] = make(map[int]string)
+			}
+			cells[rowNum][colNum] = text
+		}
+	}
+
+	return cells, nil
+}
+
+// cellText - The text of a cell based on its type
+// Numbers and dates are left as stored as the number formats aren't applied
+func (r *Reader) cellText(c *ooxml.Node) string {
+	v := c.ChildText("v")
+
+	switch c.Attr("t") {
+	case "s":
+		i, err := strconv.Atoi(v)
+		if err != nil || i < 0 || i >= len(r.sharedStrings) {
+			return ""
+		}
+		return r.sharedStrings[i]
+	case "inlineStr":
+		if is := c.Child("is"); is != nil {
+			return richText(is)
+		}
+		return ""
+	case "b":
+		if v == "1" {
+			return "TRUE"
+		}
+		return "FALSE"
+	default:
+		return v
+	}
+}
+
+// tables - Split the cells into tables at each empty row
+func tables(cells map[int]map[int]string) []Table {
+	var tables []Table
+
+	var rowNums []int
+	for rowNum := range cells {
+		rowNums = append(rowNums, rowNum)
+	}
+	sort.Ints(rowNums)
+
+	var group []int
+	addTable := func() {
+		if len(group) > 0 {
+			tables = append(tables, table(cells, group))
+		}
+		group = nil
+	}
+
+	for _, rowNum := range rowNums {
+		if len(group) > 0 && rowNum != group[len(group)-1]+1 {
+			addTable()
+		}
+		group = append(group, rowNum)
+	}
+	addTable()
+
+	return tables
+}
+
+// table - A Table covering the columns used by the rows
+func table(cells map[int]map[int]string, rowNums []int) Table {
+	t := Table{FirstCol: -1}
+
+	for _, rowNum := range rowNums {
+		for col := range cells[rowNum] {
+			if t.FirstCol == -1 || col < t.FirstCol {
+				t.FirstCol = col
+			}
+			if col > t.LastCol {
+				t.LastCol = col
+			}
+		}
+	}
+
+	for _, rowNum := range rowNums {
+		row := Row{Number: rowNum}
+		for col := t.FirstCol; col <= t.LastCol; col++ {
+			row.Cells = append(row.Cells, cells[rowNum][col])
+		}
+		t.Rows = append(t.Rows, row)
+	}
+
+	return t
+}
+
+// CellRange - The A1 style range of the table's columns between the two rows e.g. A1:D20
+func (t Table) CellRange(firstRow int, lastRow int) string {
+	return ColumnName(t.FirstCol) + strconv.Itoa(firstRow) + ":" + ColumnName(t.LastCol) + strconv.Itoa(lastRow)
+}
+
+// ColumnName - Column letters for the 1 based column number e.g. 28 is AB
+func ColumnName(col int) string {
+	name := ""
+	for col > 0 {
+		col--
+		name = string(rune('A'+col%26)) + name
+		col /= 26
+	}
+	return name
+}
+
+// splitRef - The 1 based column and row numbers of an A1 style cell reference
+func splitRef(ref string) (int, int, bool) {
+	col := 0
+	i := 0
+	for ; i < len(ref); i++ {
+		ch := ref[i]
+		if ch >= 'a' && ch <= 'z' {
+			ch -= 'a' - 'A'
+		}
+		if ch < 'A' || ch > 'Z' {
+			break
+		}
+		col = col*26 + int(ch-'A'+1)
+	}
+
+	row, err := strconv.Atoi(ref[i:])
+	if col == 0 || err != nil {
+		return 0, 0, false
+	}
+
+	return col, row, true
+}
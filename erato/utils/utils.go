@@ -30,6 +30,11 @@ func HashString(s string) string {
 	return fmt.Sprintf("%x", h.Sum(nil))
 }
 
+// HashBytes - SHA256 hash of the data e.g. to tell if content has changed
+func HashBytes(data []byte) string {
+	return fmt.Sprintf("%x", sha256.Sum256(data))
+}
+
 // PrettyStructDebug - returns a pretty printed string of a struct
 func PrettyStructDebug(v interface{}) string {
 	jv, err := json.MarshalIndent(v, "", "\t")
@@ -50,13 +55,17 @@ func PrintPrettyStructDebug(v interface{}) {
 func CheckEnvVars(envVars string) error {
 	// Check that all the required environment variables are set
 	// using the envVars const
-	// returns an error if any are missing
+	// returns an error listing all that are missing
 	// otherwise returns nil
+	var missing []string
 	for _, e := range strings.Split(envVars, ",") {
 		if os.Getenv(e) == "" {
-			return fmt.Errorf("checkEnvVars - Environment variable %s is not set", e)
+			missing = append(missing, e)
 		}
 	}
+	if len(missing) > 0 {
+		return fmt.Errorf("checkEnvVars - Environment variables are not set: %s", strings.Join(missing, ", "))
+	}
 	return nil
 }
 